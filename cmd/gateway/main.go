@@ -17,6 +17,11 @@ package main
 
 // @securityDefinitions.basic  BasicAuth
 
+// @securityDefinitions.apikey  AdminToken
+// @in                          header
+// @name                        Authorization
+// @description                 Admin token as "Bearer <admin.token>"
+
 import (
 	"context"
 	"net/http"
@@ -41,6 +46,9 @@ func main() {
 	defer logger.Sync()
 
 	loggerInstance := logger.L()
+	if err := logger.SetLevel(cfg.Env, cfg.Log.Level); err != nil {
+		loggerInstance.Error("Invalid log level, keeping the default", zap.Error(err))
+	}
 
 	// Initialize OpenTelemetry with timeout
 	initCtx, initCancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -56,6 +64,13 @@ func main() {
 		}
 	}()
 
+	// Tee logs to the OpenTelemetry exporter from here on
+	if telemetry.LoggerProvider != nil {
+		logger.Export(telemetry.LoggerProvider, cfg.ServiceName)
+		loggerInstance = logger.L()
+		telemetry.Logger = loggerInstance
+	}
+
 	loggerInstance.Info("🚀 Starting API Gateway...",
 		zap.Int("port", cfg.Port),
 		zap.String("env", cfg.Env),
@@ -67,6 +82,15 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// SIGHUP reapplies the configured log level, undoing changes made through the admin API
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			reloadLogLevel(loggerInstance)
+		}
+	}()
+
 	// Run server in a goroutine
 	go func() {
 		if err := srv.Run(); err != nil && err != http.ErrServerClosed {
@@ -92,3 +116,17 @@ func main() {
 
 	loggerInstance.Info("Server gracefully stopped")
 }
+
+// reloadLogLevel rereads the configuration and applies its log level
+func reloadLogLevel(l *zap.Logger) {
+	cfg, err := config.Reload()
+	if err != nil {
+		l.Error("Failed to reload config, keeping the current log level", zap.Error(err))
+		return
+	}
+	if err := logger.SetLevel(cfg.Env, cfg.Log.Level); err != nil {
+		l.Error("Invalid log level, keeping the current one", zap.Error(err))
+		return
+	}
+	l.Warn("Log level reloaded", zap.String("level", logger.Level().String()))
+}
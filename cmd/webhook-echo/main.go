@@ -0,0 +1,68 @@
+// Command webhook-echo is a local stand-in for a webhook receiver. It prints
+// every delivery it receives, checks the signature when a secret is given and
+// can fail deliveries on purpose to exercise retries and dead-lettering.
+//
+//	go run ./cmd/webhook-echo -addr :9000 -secret whsec_... -fail-first 2
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hohotang/shortlink-gateway/internal/webhook"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "address to listen on")
+	secret := flag.String("secret", "", "subscription secret, signatures are not checked when empty")
+	tolerance := flag.Duration("tolerance", 5*time.Minute, "maximum age of a delivery timestamp")
+	failFirst := flag.Int("fail-first", 0, "fail the first N attempts of every delivery")
+	failRate := flag.Float64("fail-rate", 0, "fraction of attempts to fail at random, between 0 and 1")
+	failStatus := flag.Int("fail-status", http.StatusServiceUnavailable, "status code of failed attempts")
+	flag.Parse()
+
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		delivery := r.Header.Get(webhook.HeaderDelivery)
+		mu.Lock()
+		attempts[delivery]++
+		attempt := attempts[delivery]
+		mu.Unlock()
+
+		signature := "unchecked"
+		if *secret != "" {
+			err := webhook.Verify(*secret, r.Header.Get(webhook.HeaderSignature), r.Header.Get(webhook.HeaderTimestamp), body, time.Now(), *tolerance)
+			if err != nil {
+				log.Printf("%s %s attempt %d: rejected: %v", r.Header.Get(webhook.HeaderEvent), delivery, attempt, err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			signature = "valid"
+		}
+
+		if attempt <= *failFirst || rand.Float64() < *failRate {
+			log.Printf("%s %s attempt %d: failing with %d", r.Header.Get(webhook.HeaderEvent), delivery, attempt, *failStatus)
+			w.WriteHeader(*failStatus)
+			return
+		}
+
+		log.Printf("%s %s attempt %d: signature %s\n%s", r.Header.Get(webhook.HeaderEvent), delivery, attempt, signature, body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("Listening for webhooks on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
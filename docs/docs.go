@@ -24,30 +24,957 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/v1/admin/debug-token": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Returns a token that turns on debug logging for every request carrying it in the debug header (X-Debug by default), whatever the log level, until it expires.\ntoken = exp + \".\" + hex(HMAC-SHA256(log.debug_secret, \"debug.\" + exp)) with exp as a Unix timestamp.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Mint a debug token",
+                "parameters": [
+                    {
+                        "description": "Lifetime of the token, 15m by default",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/model.DebugTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the header name, the token and its expiry",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request, or debug tokens are not enabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Admin API is disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/log-level": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Returns the level of the gateway logger.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get the log level",
+                "responses": {
+                    "200": {
+                        "description": "Returns the level",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Admin API is disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Changes the level of the gateway logger until the next restart or SIGHUP, which reapplies log.level from the configuration.\nThe level is one of debug, info, warn or error.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Change the log level",
+                "parameters": [
+                    {
+                        "description": "New level",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.LogLevelRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the new level",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request, or an unknown level",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Admin API is disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/v1/expand/{shortID}": {
             "get": {
-                "description": "Redirects to the original URL from a short URL ID",
+                "description": "Redirects to the original URL from a short URL ID.\nTargeting rules stored with the link may pick a different destination by country, device OS, language or time.\nLinks with split variants send each visitor to a weighted destination, optionally kept sticky via a cookie.\nKnown link unfurlers may receive an HTML metadata page instead of a redirect.\nPassword protected links serve a challenge page unless a valid unlock cookie or the X-Link-Password header is sent.\nSigned URLs carry exp, kid and sig query parameters which are verified before the link is looked up (see POST /v1/links/{shortID}/sign).\nLinks created as signed-only reject unsigned requests.\nA \"+\" suffix on the short ID (e.g. abc123+) shows a preview page with the destination, creation date and click count instead of redirecting.\nLinks flagged with interstitial, and external destinations when configured, show a warning page that optionally redirects after a countdown.\nDestinations that have since been blocklisted get a block page (or the warning page, depending on configuration).\nChains of short links that loop back on themselves are answered with 508 Loop Detected.\nThe redirect status (301, 302, 307 or 308) and whether the query string is forwarded to the destination are configurable globally and per link.\nPermanent redirects of links that are the same for every visitor are cacheable; all other redirects are sent with Cache-Control: private, no-store.",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Expand a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password of a protected link",
+                        "name": "X-Link-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Expiry of a signed URL as a Unix timestamp",
+                        "name": "exp",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the key a signed URL was signed with",
+                        "name": "kid",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "HMAC-SHA256 signature of a signed URL, base64url encoded",
+                        "name": "sig",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Metadata page for link unfurlers, preview page or interstitial warning page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "301": {
+                        "description": "Permanent redirect to original URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "302": {
+                        "description": "Redirect to original URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "307": {
+                        "description": "Temporary redirect to original URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "308": {
+                        "description": "Permanent redirect to original URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Password challenge page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Signature invalid or required, or blocked destination page",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "410": {
+                        "description": "Signed URL expired",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too many password attempts",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "508": {
+                        "description": "Redirect loop detected",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Verifies the password posted from the challenge page. On success a short-lived signed cookie is set and the client is sent back to the short URL.\nAttempts are rate limited per client and link, and repeated failures lock the client out temporarily.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Unlock a password protected short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Link password",
+                        "name": "password",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "303": {
+                        "description": "Redirect back to the short URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Challenge page with an error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many attempts",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/links/{shortID}/qr": {
+            "get": {
+                "description": "Renders the short URL as a QR code image. Responses carry an ETag and can be revalidated with If-None-Match.",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "QR code for a short link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "png",
+                            "svg"
+                        ],
+                        "type": "string",
+                        "default": "png",
+                        "description": "Image format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 256,
+                        "description": "Width and height in pixels",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "L",
+                            "M",
+                            "Q",
+                            "H"
+                        ],
+                        "type": "string",
+                        "default": "M",
+                        "description": "Error correction level",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 4,
+                        "description": "Quiet zone in modules",
+                        "name": "margin",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "000000",
+                        "description": "Foreground color as hex",
+                        "name": "fg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "ffffff",
+                        "description": "Background color as hex",
+                        "name": "bg",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "QR code image",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request, or content too long for a QR code",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/links/{shortID}/sign": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Mints a short URL that is only valid until it expires. The signature is carried in the exp, kid and sig query parameters:\nexp is the expiry as a Unix timestamp, kid names the signing key and\nsig = base64url(HMAC-SHA256(key[kid], \"v1\\n\" + kid + \"\\n\" + shortID + \"\\n\" + exp)) without padding.\nSignatures made with any configured key are accepted, so keys can be rotated by adding a new key ID before retiring the old one.\nWebhook subscribers receive link.updated now and link.expired when the signed URL expires.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Sign a short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL ID",
+                        "name": "shortID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Lifetime of the signed URL",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/model.SignedOptions"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the signed URL, its expiry and the key ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Admin API is disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Short URL not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/shorten": {
+            "post": {
+                "description": "Creates a short URL from a long URL.\nAn optional utm block, or a campaign preset from the gateway config, appends utm_* parameters to every destination; existing utm_* parameters are kept unless override is set.\nWith reuse_existing the caller's oldest link with the same normalized URL and settings is returned instead of creating a new one, and reused is set in the response. Password protected links are never reused.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Shorten a URL",
+                "parameters": [
+                    {
+                        "description": "URL to shorten",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ShortenRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replays the first response to retries with the same key for idempotency.ttl (24h by default)",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns shortened URL, the QR code as a data URI and a signed URL if requested",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request, or a blocked or short URL destination with its reason code",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same idempotency key is still in progress",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency key reused with a different request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook subscriptions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/webhook.Subscription"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Admin API is disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Registers an endpoint that receives link events as signed JSON POST requests. Every request carries the\nX-Shortlink-Event, X-Shortlink-Delivery, X-Shortlink-Timestamp and X-Shortlink-Signature headers, where\nsignature = \"sha256=\" + hex(HMAC-SHA256(secret, timestamp + \".\" + body)). Failed deliveries are retried with\nexponential backoff and written to the dead letter file once the attempts are exhausted.\nThe secret is only returned in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Subscribe to link events",
+                "parameters": [
+                    {
+                        "description": "Endpoint and events",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.WebhookSubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Admin API is disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get a webhook subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Subscription"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Admin API is disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Stops deliveries to the endpoint. Pending deliveries are dead-lettered.",
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete a webhook subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Admin API is disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Returns the most recent deliveries of a subscription, newest first, with their attempts, last response and payload.\nDead-lettered deliveries are also kept in the dead letter file after they leave the log.",
                 "produces": [
-                    "text/html"
+                    "application/json"
                 ],
                 "tags": [
-                    "urls"
+                    "webhooks"
                 ],
-                "summary": "Expand a short URL",
+                "summary": "Webhook delivery log",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Short URL ID",
-                        "name": "shortID",
+                        "description": "Subscription ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "succeeded",
+                            "dead"
+                        ],
+                        "type": "string",
+                        "description": "Only deliveries in this state",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Maximum number of deliveries",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "302": {
-                        "description": "Redirect to original URL",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/webhook.Delivery"
+                            }
                         }
                     },
                     "400": {
@@ -59,8 +986,26 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Admin API is disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -71,33 +1016,125 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/shorten": {
+        "/v1/webhooks/{id}/deliveries/{deliveryID}/replay": {
             "post": {
-                "description": "Creates a short URL from a long URL",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "Sends the event of a succeeded or dead-lettered delivery again as a new delivery with the same event ID,\nso receivers can deduplicate it. Deliveries are looked up in the delivery log and then in the dead letter file.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "urls"
+                    "webhooks"
                 ],
-                "summary": "Shorten a URL",
+                "summary": "Replay a webhook delivery",
                 "parameters": [
                     {
-                        "description": "URL to shorten",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delivery ID",
+                        "name": "deliveryID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/model.ShortenRequest"
+                            "$ref": "#/definitions/webhook.Delivery"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Admin API is disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Delivery is still pending",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Gateway is shutting down",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
+                }
+            }
+        },
+        "/v1/webhooks/{id}/ping": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Queues a webhook.ping event for the subscription, regardless of the events it subscribed to.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Send a test event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Returns shortened URL",
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Delivery"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -105,8 +1142,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Admin API is disabled",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -114,8 +1151,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Gateway is shutting down",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -128,16 +1174,479 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "model.DebugTokenRequest": {
+            "type": "object",
+            "properties": {
+                "expires_in": {
+                    "description": "Go duration until the token expires",
+                    "type": "string",
+                    "example": "15m"
+                }
+            }
+        },
+        "model.FragmentMode": {
+            "type": "string",
+            "enum": [
+                "destination",
+                "visitor"
+            ],
+            "x-enum-comments": {
+                "FragmentDestination": "keep the destination's fragment",
+                "FragmentVisitor": "drop it so the short URL's fragment is carried over"
+            },
+            "x-enum-varnames": [
+                "FragmentDestination",
+                "FragmentVisitor"
+            ]
+        },
+        "model.LogLevelRequest": {
+            "type": "object",
+            "required": [
+                "level"
+            ],
+            "properties": {
+                "level": {
+                    "type": "string",
+                    "enum": [
+                        "debug",
+                        "info",
+                        "warn",
+                        "error"
+                    ]
+                }
+            }
+        },
+        "model.QueryMode": {
+            "type": "string",
+            "enum": [
+                "off",
+                "keep",
+                "override",
+                "append"
+            ],
+            "x-enum-comments": {
+                "QueryAppend": "conflicting keys keep both values",
+                "QueryKeep": "the destination's value wins for conflicting keys",
+                "QueryOff": "do not forward the query string",
+                "QueryOverride": "the incoming value wins for conflicting keys"
+            },
+            "x-enum-varnames": [
+                "QueryOff",
+                "QueryKeep",
+                "QueryOverride",
+                "QueryAppend"
+            ]
+        },
+        "model.RedirectOptions": {
+            "type": "object",
+            "properties": {
+                "fragment": {
+                    "description": "whose fragment the visitor ends up on",
+                    "enum": [
+                        "destination",
+                        "visitor"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.FragmentMode"
+                        }
+                    ]
+                },
+                "query": {
+                    "description": "forward the short URL's query string",
+                    "enum": [
+                        "off",
+                        "keep",
+                        "override",
+                        "append"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.QueryMode"
+                        }
+                    ]
+                },
+                "status": {
+                    "description": "HTTP status of the redirect",
+                    "type": "integer",
+                    "enum": [
+                        301,
+                        302,
+                        307,
+                        308
+                    ]
+                }
+            }
+        },
+        "model.RedirectRule": {
+            "type": "object",
+            "properties": {
+                "countries": {
+                    "description": "ISO 3166-1 alpha-2 codes",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "US",
+                        "CA"
+                    ]
+                },
+                "destination_url": {
+                    "type": "string",
+                    "example": "https://apps.apple.com/app/id0"
+                },
+                "device_os": {
+                    "description": "client operating systems",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "iOS"
+                    ]
+                },
+                "languages": {
+                    "description": "primary language subtags",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "en"
+                    ]
+                },
+                "not_after": {
+                    "description": "end of the active time window",
+                    "type": "string"
+                },
+                "not_before": {
+                    "description": "start of the active time window",
+                    "type": "string"
+                }
+            }
+        },
         "model.ShortenRequest": {
             "type": "object",
             "properties": {
+                "interstitial": {
+                    "description": "show a warning page before redirecting",
+                    "type": "boolean"
+                },
                 "original_url": {
                     "type": "string"
+                },
+                "password": {
+                    "description": "protect the link with a password, at most 72 bytes",
+                    "type": "string",
+                    "maxLength": 72
+                },
+                "qr_code": {
+                    "description": "also return a PNG QR code as a data URI",
+                    "type": "boolean"
+                },
+                "redirect": {
+                    "description": "override the gateway's redirect defaults",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.RedirectOptions"
+                        }
+                    ]
+                },
+                "reuse_existing": {
+                    "description": "return the caller's existing link for the same URL and settings",
+                    "type": "boolean"
+                },
+                "rules": {
+                    "description": "optional targeting rules, evaluated in order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.RedirectRule"
+                    }
+                },
+                "signed": {
+                    "description": "only allow access through signed, expiring URLs",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.SignedOptions"
+                        }
+                    ]
+                },
+                "stickiness": {
+                    "description": "how variant assignment is kept per visitor",
+                    "enum": [
+                        "none",
+                        "cookie",
+                        "hash"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Stickiness"
+                        }
+                    ]
+                },
+                "utm": {
+                    "description": "append utm_* parameters to the destinations",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.UTMOptions"
+                        }
+                    ]
+                },
+                "variants": {
+                    "description": "optional A/B split destinations",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Variant"
+                    }
+                }
+            }
+        },
+        "model.SignedOptions": {
+            "type": "object",
+            "properties": {
+                "expires_in": {
+                    "description": "Go duration until the signed URL expires",
+                    "type": "string",
+                    "example": "24h"
+                }
+            }
+        },
+        "model.Stickiness": {
+            "type": "string",
+            "enum": [
+                "none",
+                "cookie",
+                "hash"
+            ],
+            "x-enum-varnames": [
+                "StickinessNone",
+                "StickinessCookie",
+                "StickinessHash"
+            ]
+        },
+        "model.UTMOptions": {
+            "type": "object",
+            "properties": {
+                "campaign": {
+                    "type": "string",
+                    "example": "spring_sale"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "medium": {
+                    "type": "string",
+                    "example": "email"
+                },
+                "override": {
+                    "description": "replace utm_* parameters already present in a destination",
+                    "type": "boolean"
+                },
+                "preset": {
+                    "description": "campaign preset defined in the gateway config",
+                    "type": "string",
+                    "example": "newsletter"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "newsletter"
+                },
+                "term": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Variant": {
+            "type": "object",
+            "properties": {
+                "destination_url": {
+                    "type": "string",
+                    "example": "https://example.com/landing-b"
+                },
+                "name": {
+                    "description": "up to 32 letters, digits, '-', '_' or '.'",
+                    "type": "string",
+                    "example": "B"
+                },
+                "weight": {
+                    "type": "integer",
+                    "example": 50
+                }
+            }
+        },
+        "model.WebhookSubscriptionRequest": {
+            "type": "object",
+            "properties": {
+                "click_thresholds": {
+                    "description": "human click counts that trigger link.click_threshold, counted per gateway process",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        100,
+                        1000
+                    ]
+                },
+                "description": {
+                    "type": "string"
+                },
+                "events": {
+                    "description": "empty subscribes to every event",
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "link.created",
+                            "link.updated",
+                            "link.expired",
+                            "link.click_threshold"
+                        ]
+                    }
+                },
+                "secret": {
+                    "description": "HMAC key of the signatures, generated if empty",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://crm.example.com/hooks/shortlink"
+                }
+            }
+        },
+        "webhook.Delivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "description": "why the last attempt failed",
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "$ref": "#/definitions/webhook.EventType"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "replay_of": {
+                    "description": "delivery this one replays",
+                    "type": "string"
+                },
+                "response_status": {
+                    "description": "status of the last response",
+                    "type": "integer"
+                },
+                "status": {
+                    "$ref": "#/definitions/webhook.Status"
+                },
+                "subscription_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "webhook.EventType": {
+            "type": "string",
+            "enum": [
+                "link.created",
+                "link.updated",
+                "link.expired",
+                "link.click_threshold",
+                "webhook.ping"
+            ],
+            "x-enum-comments": {
+                "EventClickThreshold": "a short link reached one of the subscription's click thresholds",
+                "EventLinkCreated": "a new short link was created",
+                "EventLinkExpired": "a signed URL of a short link expired",
+                "EventLinkUpdated": "a short link was changed through the gateway, such as a new signed URL",
+                "EventPing": "test event sent on request, to one subscription only"
+            },
+            "x-enum-varnames": [
+                "EventLinkCreated",
+                "EventLinkUpdated",
+                "EventLinkExpired",
+                "EventClickThreshold",
+                "EventPing"
+            ]
+        },
+        "webhook.Status": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "succeeded",
+                "dead"
+            ],
+            "x-enum-comments": {
+                "StatusDead": "attempts exhausted or not retryable, written to the dead letter file",
+                "StatusPending": "queued or waiting for a retry",
+                "StatusSucceeded": "the endpoint answered with a 2xx status"
+            },
+            "x-enum-varnames": [
+                "StatusPending",
+                "StatusSucceeded",
+                "StatusDead"
+            ]
+        },
+        "webhook.Subscription": {
+            "type": "object",
+            "properties": {
+                "click_thresholds": {
+                    "description": "human click counts that trigger link.click_threshold",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "events": {
+                    "description": "empty receives every event",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/webhook.EventType"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "HMAC key of the signatures, only returned on creation",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
                 }
             }
         }
     },
     "securityDefinitions": {
+        "AdminToken": {
+            "description": "Admin token as \"Bearer \u003cadmin.token\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        },
         "BasicAuth": {
             "type": "basic"
         }
@@ -0,0 +1,184 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Format is the layout of access log lines
+type Format string
+
+const (
+	FormatCommon   Format = "common"   // NCSA Common Log Format
+	FormatCombined Format = "combined" // Common Log Format with referer and user agent
+	FormatJSON     Format = "json"     // one JSON object per line
+)
+
+// ParseFormat validates the name of a format
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case FormatCommon, FormatCombined, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown access log format %q", name)
+}
+
+// Entry describes one request
+type Entry struct {
+	Time      time.Time     `json:"time"`
+	RemoteIP  string        `json:"remote_ip"`
+	User      string        `json:"user,omitempty"`
+	Method    string        `json:"method"`
+	URI       string        `json:"uri"`
+	Proto     string        `json:"proto"`
+	Status    int           `json:"status"`
+	Bytes     int           `json:"bytes"`
+	Duration  time.Duration `json:"-"`
+	Referer   string        `json:"referer,omitempty"`
+	UserAgent string        `json:"user_agent,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+	TraceID   string        `json:"trace_id,omitempty"`
+}
+
+// Config configures a Logger
+type Config struct {
+	Format     Format
+	Path       string
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+	Compress   bool
+}
+
+// Logger writes access log lines to a file that is rotated by size
+type Logger struct {
+	format Format
+	out    io.WriteCloser
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// New creates a Logger. The file is opened on the first write.
+func New(cfg Config) *Logger {
+	return &Logger{
+		format: cfg.Format,
+		out: &lumberjack.Logger{
+			Filename:   cfg.Path,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAgeDays,
+			Compress:   cfg.Compress,
+		},
+	}
+}
+
+// Log writes one line for e
+func (l *Logger) Log(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf.Reset()
+	switch l.format {
+	case FormatJSON:
+		if err := writeJSON(&l.buf, e); err != nil {
+			return err
+		}
+	case FormatCommon:
+		writeCommon(&l.buf, e)
+		l.buf.WriteByte('\n')
+	default:
+		writeCommon(&l.buf, e)
+		l.buf.WriteString(` "`)
+		l.buf.WriteString(escape(e.Referer))
+		l.buf.WriteString(`" "`)
+		l.buf.WriteString(escape(e.UserAgent))
+		l.buf.WriteString("\"\n")
+	}
+	_, err := l.out.Write(l.buf.Bytes())
+	return err
+}
+
+// Close closes the current file
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.out.Close()
+}
+
+// writeCommon writes: host ident user [time] "request" status bytes
+func writeCommon(buf *bytes.Buffer, e Entry) {
+	buf.WriteString(dash(e.RemoteIP))
+	buf.WriteString(" - ")
+	buf.WriteString(dash(escape(e.User)))
+	buf.WriteString(" [")
+	buf.WriteString(e.Time.Format("02/Jan/2006:15:04:05 -0700"))
+	buf.WriteString(`] "`)
+	buf.WriteString(escape(e.Method))
+	buf.WriteByte(' ')
+	buf.WriteString(escape(e.URI))
+	buf.WriteByte(' ')
+	buf.WriteString(escape(e.Proto))
+	buf.WriteString(`" `)
+	buf.WriteString(strconv.Itoa(e.Status))
+	buf.WriteByte(' ')
+	if e.Bytes > 0 {
+		buf.WriteString(strconv.Itoa(e.Bytes))
+	} else {
+		buf.WriteByte('-')
+	}
+}
+
+func writeJSON(buf *bytes.Buffer, e Entry) error {
+	line := struct {
+		Entry
+		DurationMS float64 `json:"duration_ms"`
+	}{e, float64(e.Duration.Microseconds()) / 1000}
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(line)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// escape keeps client supplied values from breaking the line format by
+// escaping quotes, backslashes and control characters
+func escape(s string) string {
+	clean := true
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == '"' || c == '\\' || c < 0x20 || c == 0x7f {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return s
+	}
+
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
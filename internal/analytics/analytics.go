@@ -0,0 +1,143 @@
+package analytics
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/hohotang/shortlink-gateway/internal/cache"
+	"github.com/hohotang/shortlink-gateway/internal/geoip"
+	"github.com/hohotang/shortlink-gateway/internal/otel"
+	"github.com/hohotang/shortlink-gateway/internal/useragent"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ClickEvent describes a single resolution of a short link
+type ClickEvent struct {
+	ShortID   string
+	Time      time.Time
+	ClientIP  string
+	UserAgent string
+	Referer   string
+	Client    useragent.Info
+	Location  geoip.Location
+	Variant   string // chosen split variant, empty for links without variants
+	Prefetch  bool
+}
+
+// Human reports whether the click came from a person, neither a bot nor a prefetch
+func (e ClickEvent) Human() bool {
+	return !e.Client.Bot && !e.Prefetch
+}
+
+// visitorKey identifies a visitor without keeping the raw IP and user agent in memory
+func (e ClickEvent) visitorKey() uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(e.ClientIP))
+	h.Write([]byte{0})
+	h.Write([]byte(e.UserAgent))
+	return h.Sum64()
+}
+
+// Stats holds the click counters of a single short link
+type Stats struct {
+	Clicks         int64     `json:"clicks"`
+	BotClicks      int64     `json:"bot_clicks"`
+	UniqueVisitors int64     `json:"unique_visitors"`
+	LastClickAt    time.Time `json:"last_click_at"`
+}
+
+type linkStats struct {
+	Stats
+	visitors map[uint64]struct{}
+}
+
+// Recorder keeps in-memory click statistics per short link and exports click metrics.
+// Statistics are kept for at most size links, and dropped for links not clicked
+// within ttl, so that requests for made-up short IDs cannot grow memory.
+type Recorder struct {
+	mu          sync.Mutex
+	links       *cache.LRU[string, *linkStats]
+	maxVisitors int
+	metrics     *otel.Metrics
+}
+
+// NewRecorder creates a click recorder that reports to the given metric instruments.
+// Once maxVisitors unique visitors of a link are tracked, further new visitors
+// are still counted as clicks but not as unique.
+func NewRecorder(size int, ttl time.Duration, maxVisitors int, metrics *otel.Metrics) *Recorder {
+	return &Recorder{
+		links:       cache.NewLRU[string, *linkStats](size, ttl),
+		maxVisitors: maxVisitors,
+		metrics:     metrics,
+	}
+}
+
+// Record counts a click and returns the link's statistics including it. Bot and
+// prefetch clicks are counted but never treated as unique visitors.
+func (r *Recorder) Record(ctx context.Context, event ClickEvent) Stats {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	unique := false
+
+	r.mu.Lock()
+	stats, ok := r.links.Get(event.ShortID)
+	if !ok {
+		stats = &linkStats{visitors: make(map[uint64]struct{})}
+	}
+	// Set on every click, so that the statistics of a clicked link do not expire
+	r.links.Set(event.ShortID, stats)
+	stats.Clicks++
+	stats.LastClickAt = event.Time
+	if !event.Human() {
+		stats.BotClicks++
+	} else {
+		key := event.visitorKey()
+		if _, seen := stats.visitors[key]; !seen && len(stats.visitors) < r.maxVisitors {
+			stats.visitors[key] = struct{}{}
+			stats.UniqueVisitors++
+			unique = true
+		}
+	}
+	current := stats.Stats
+	r.mu.Unlock()
+
+	if r.metrics == nil {
+		return current
+	}
+
+	attrs := metric.WithAttributes(
+		attribute.Bool("bot", event.Client.Bot),
+		attribute.String("bot_kind", string(event.Client.BotKind)),
+		attribute.String("device", string(event.Client.Device)),
+		attribute.Bool("prefetch", event.Prefetch),
+		attribute.String("country", event.Location.Country),
+	)
+	r.metrics.ClickCounter.Add(ctx, 1, attrs)
+	if event.Variant != "" {
+		r.metrics.VariantClick(ctx, event.Variant, event.Client.Bot)
+	}
+	if unique {
+		r.metrics.UniqueVisitors.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("device", string(event.Client.Device)),
+		))
+	}
+	return current
+}
+
+// Stats returns the click statistics recorded for a short link
+func (r *Recorder) Stats(shortID string) Stats {
+	stats, ok := r.links.Get(shortID)
+	if !ok {
+		return Stats{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return stats.Stats
+}
@@ -0,0 +1,158 @@
+package blocklist
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/hohotang/shortlink-gateway/internal/filewatch"
+	"github.com/hohotang/shortlink-gateway/internal/otel"
+	"github.com/hohotang/shortlink-gateway/internal/urlutil"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// Reason is the machine readable code of a block decision
+type Reason string
+
+const (
+	ReasonInvalidURL  Reason = "invalid_url"
+	ReasonDomain      Reason = "domain"
+	ReasonSuffix      Reason = "suffix"
+	ReasonPattern     Reason = "pattern"
+	ReasonMixedScript Reason = "idn_mixed_script"
+	ReasonHomograph   Reason = "idn_homograph"
+)
+
+// Stage is where a URL is checked
+type Stage string
+
+const (
+	StageShorten Stage = "shorten"
+	StageExpand  Stage = "expand"
+)
+
+// Verdict is the result of checking a URL. The zero value allows the URL.
+type Verdict struct {
+	Reason Reason
+	Rule   string // the domain, suffix, pattern or protected domain that matched
+}
+
+// Blocked reports whether the URL must not be used
+func (v Verdict) Blocked() bool {
+	return v.Reason != ""
+}
+
+// Config configures a Blocklist
+type Config struct {
+	DomainsPath      string        // hosts blocked exactly, one per line
+	SuffixesPath     string        // domains blocked together with all their subdomains, one per line
+	PatternsPath     string        // regular expressions matched against the normalised URL, one per line
+	ReloadInterval   time.Duration // how often the files are checked for changes
+	ProtectedDomains []string      // domains whose lookalikes are blocked
+	BlockMixedScript bool          // block hosts mixing Latin and lookalike scripts in one label
+}
+
+// Blocklist decides whether destination URLs are known or likely to be malicious.
+// Rule files are reloaded when they change.
+type Blocklist struct {
+	cfg       Config
+	protected []string
+	rules     atomic.Pointer[ruleSet]
+	watchers  []*filewatch.Watcher
+	metrics   *otel.Metrics
+	logger    *zap.Logger
+}
+
+// New loads the blocklist files and starts watching them
+func New(cfg Config, metrics *otel.Metrics, logger *zap.Logger) (*Blocklist, error) {
+	rules, err := loadRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Blocklist{
+		cfg:     cfg,
+		metrics: metrics,
+		logger:  logger,
+	}
+	b.rules.Store(rules)
+
+	for _, domain := range cfg.ProtectedDomains {
+		if host, err := urlutil.NormalizeHost(domain); err == nil && host != "" {
+			b.protected = append(b.protected, host)
+		}
+	}
+
+	for _, path := range []string{cfg.DomainsPath, cfg.SuffixesPath, cfg.PatternsPath} {
+		if path != "" {
+			b.watchers = append(b.watchers, filewatch.New(path, cfg.ReloadInterval, b.reload))
+		}
+	}
+
+	return b, nil
+}
+
+// Check decides whether raw may be used as a destination and records the decision
+func (b *Blocklist) Check(ctx context.Context, stage Stage, raw string) Verdict {
+	verdict := b.check(raw)
+
+	if b.metrics != nil {
+		result := "allowed"
+		if verdict.Blocked() {
+			result = "blocked"
+		}
+		b.metrics.BlockDecisions.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("stage", string(stage)),
+			attribute.String("result", result),
+			attribute.String("reason", string(verdict.Reason)),
+		))
+	}
+
+	return verdict
+}
+
+func (b *Blocklist) check(raw string) Verdict {
+	u, err := urlutil.Normalize(raw)
+	if err != nil {
+		return Verdict{Reason: ReasonInvalidURL}
+	}
+	host := u.Hostname()
+
+	if reason, rule := b.rules.Load().match(u.String(), host); reason != "" {
+		return Verdict{Reason: reason, Rule: rule}
+	}
+
+	if b.cfg.BlockMixedScript && mixedScript(urlutil.UnicodeHost(host)) {
+		return Verdict{Reason: ReasonMixedScript, Rule: host}
+	}
+	if domain := lookalikeOf(host, b.protected); domain != "" {
+		return Verdict{Reason: ReasonHomograph, Rule: domain}
+	}
+
+	return Verdict{}
+}
+
+// reload swaps in the rules from disk, keeping the previous rules if a file is invalid
+func (b *Blocklist) reload() {
+	rules, err := loadRules(b.cfg)
+	if err != nil {
+		b.logger.Error("Failed to reload blocklist, keeping previous rules", zap.Error(err))
+		return
+	}
+	b.rules.Store(rules)
+	b.logger.Info("Reloaded blocklist",
+		zap.Int("domains", len(rules.domains)),
+		zap.Int("suffixes", len(rules.suffixes)),
+		zap.Int("patterns", len(rules.patterns)),
+	)
+}
+
+// Close stops watching the blocklist files
+func (b *Blocklist) Close() error {
+	for _, w := range b.watchers {
+		w.Close()
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package blocklist
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/hohotang/shortlink-gateway/internal/urlutil"
+)
+
+// confusables maps characters commonly used to imitate Latin letters to the
+// letter they resemble. It covers the usual Cyrillic, Greek and Latin
+// lookalikes and digits rather than the full Unicode confusables table.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'ё': 'e', 'һ': 'h', 'і': 'i', 'ї': 'i',
+	'ј': 'j', 'к': 'k', 'ӏ': 'l', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'г': 'r',
+	'ѕ': 's', 'т': 't', 'у': 'y', 'ѵ': 'v', 'ԝ': 'w', 'х': 'x', 'ү': 'y', 'ɡ': 'g',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+	// Latin with diacritics or alternative forms
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'ç': 'c', 'è': 'e', 'é': 'e',
+	'ê': 'e', 'ë': 'e', 'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i', 'ı': 'i', 'ñ': 'n', 'ò': 'o',
+	'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o', 'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u',
+	'ý': 'y', 'ÿ': 'y', 'ł': 'l', 'ſ': 's',
+	// Digits
+	'0': 'o', '1': 'l',
+}
+
+// multiConfusables are ASCII sequences that read as a single letter
+var multiConfusables = strings.NewReplacer("rn", "m", "vv", "w", "cl", "d")
+
+// skeleton reduces a Unicode host to the ASCII letters it looks like, so that
+// lookalikes of a domain share its skeleton
+func skeleton(host string) string {
+	var b strings.Builder
+	for _, r := range host {
+		if c, ok := confusables[r]; ok {
+			r = c
+		}
+		b.WriteRune(r)
+	}
+	return multiConfusables.Replace(b.String())
+}
+
+// lookalikeScripts are scripts with letters that are easily mistaken for Latin ones
+var lookalikeScripts = []*unicode.RangeTable{unicode.Cyrillic, unicode.Greek, unicode.Armenian, unicode.Cherokee}
+
+// mixedScript reports whether a label of the Unicode host mixes Latin letters with
+// letters of a lookalike script, the typical shape of a homograph attack
+func mixedScript(host string) bool {
+	for _, label := range strings.Split(host, ".") {
+		latin, lookalike := false, false
+		for _, r := range label {
+			switch {
+			case unicode.Is(unicode.Latin, r):
+				latin = true
+			case unicode.IsOneOf(lookalikeScripts, r):
+				lookalike = true
+			}
+		}
+		if latin && lookalike {
+			return true
+		}
+	}
+	return false
+}
+
+// lookalikeOf returns the protected domain that host imitates, or "" if it
+// imitates none. Hosts that are the protected domain or one of its subdomains
+// are genuine and never reported.
+func lookalikeOf(host string, protected []string) string {
+	skel := skeleton(urlutil.UnicodeHost(host))
+	for _, domain := range protected {
+		if urlutil.MatchesDomain(host, domain) {
+			return ""
+		}
+		if urlutil.MatchesDomain(skel, skeleton(domain)) {
+			return domain
+		}
+	}
+	return ""
+}
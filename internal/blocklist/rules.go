@@ -0,0 +1,105 @@
+package blocklist
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/hohotang/shortlink-gateway/internal/urlutil"
+)
+
+// ruleSet is an immutable snapshot of the blocklist files
+type ruleSet struct {
+	domains  map[string]struct{}
+	suffixes []string
+	patterns []*regexp.Regexp
+}
+
+// loadRules reads the domain, suffix and pattern files. Empty paths are skipped.
+func loadRules(cfg Config) (*ruleSet, error) {
+	rules := &ruleSet{domains: make(map[string]struct{})}
+
+	err := readLines(cfg.DomainsPath, func(line string) error {
+		host, err := urlutil.NormalizeHost(line)
+		if err != nil {
+			return err
+		}
+		rules.domains[host] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readLines(cfg.SuffixesPath, func(line string) error {
+		suffix, err := urlutil.NormalizeHost(strings.TrimPrefix(line, "."))
+		if err != nil {
+			return err
+		}
+		rules.suffixes = append(rules.suffixes, suffix)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readLines(cfg.PatternsPath, func(line string) error {
+		re, err := regexp.Compile(line)
+		if err != nil {
+			return err
+		}
+		rules.patterns = append(rules.patterns, re)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// readLines calls fn for every non-empty line of the file at path that is not a
+// "#" comment, with surrounding whitespace removed
+func readLines(path string, fn func(line string) error) error {
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// match returns the reason and the rule that blocks u, or "" when no rule matches
+func (r *ruleSet) match(u string, host string) (Reason, string) {
+	if _, ok := r.domains[host]; ok {
+		return ReasonDomain, host
+	}
+	for _, suffix := range r.suffixes {
+		if urlutil.MatchesDomain(host, suffix) {
+			return ReasonSuffix, suffix
+		}
+	}
+	for _, re := range r.patterns {
+		if re.MatchString(u) {
+			return ReasonPattern, re.String()
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,225 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/urlutil"
+)
+
+var (
+	// ErrLoop means following a chain of short links leads back to a link already visited
+	ErrLoop = errors.New("redirect loop")
+	// ErrTooManyHops means the chain is longer than the configured maximum
+	ErrTooManyHops = errors.New("too many redirect hops")
+	// ErrUnresolvable means a short URL in the chain could not be resolved to a fixed target
+	ErrUnresolvable = errors.New("short URL cannot be resolved")
+)
+
+// Kind classifies a destination URL
+type Kind string
+
+const (
+	KindNone      Kind = ""                // an ordinary destination
+	KindOwn       Kind = "own_short_url"   // one of our own short URLs
+	KindShortener Kind = "known_shortener" // a URL of another URL shortener
+)
+
+// Expander looks up links stored by the core, it is implemented by service.URLService
+type Expander interface {
+	ExpandURL(ctx context.Context, shortID string) (*model.Link, error)
+}
+
+// Config configures a Resolver
+type Config struct {
+	OwnDomains  []string      // hosts serving our short URLs, matched exactly
+	Shorteners  []string      // hosts of other URL shorteners, including their subdomains
+	MaxHops     int           // longest chain that is followed before giving up
+	HTTPTimeout time.Duration // timeout of each request to another shortener
+}
+
+// Resolver detects destinations that are themselves short URLs and follows such
+// chains. Only hosts of known shorteners are ever requested, so arbitrary
+// destinations are never fetched.
+type Resolver struct {
+	own        []string
+	shorteners []string
+	maxHops    int
+	links      Expander
+	client     *http.Client
+}
+
+// NewResolver creates a resolver that looks up our own short links through links
+func NewResolver(cfg Config, links Expander) *Resolver {
+	return &Resolver{
+		own:        normalizeHosts(cfg.OwnDomains),
+		shorteners: normalizeHosts(cfg.Shorteners),
+		maxHops:    cfg.MaxHops,
+		links:      links,
+		client: &http.Client{
+			Timeout: cfg.HTTPTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Classify reports whether raw is one of our short URLs or another shortener's
+func (r *Resolver) Classify(raw string) Kind {
+	u, err := urlutil.Normalize(raw)
+	if err != nil {
+		return KindNone
+	}
+	if _, ok := r.ownShortID(u); ok {
+		return KindOwn
+	}
+	if matchesAny(u.Hostname(), r.shorteners) {
+		return KindShortener
+	}
+	return KindNone
+}
+
+// Resolve follows raw through our own links and other shorteners until it
+// reaches a URL that is not a short URL, and returns that URL. Own links with
+// targeting rules, split variants or access restrictions have no single
+// target and cannot be resolved.
+func (r *Resolver) Resolve(ctx context.Context, raw string) (string, error) {
+	seen := make(map[string]bool)
+	current := raw
+
+	for hops := 0; ; hops++ {
+		u, err := urlutil.Normalize(current)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrUnresolvable, err)
+		}
+
+		key := u.String()
+		if seen[key] {
+			return "", ErrLoop
+		}
+		seen[key] = true
+
+		shortID, own := r.ownShortID(u)
+		if !own && !matchesAny(u.Hostname(), r.shorteners) {
+			return current, nil
+		}
+		if hops >= r.maxHops {
+			return "", ErrTooManyHops
+		}
+
+		if own {
+			current, err = r.resolveOwn(ctx, shortID)
+		} else {
+			current, err = r.resolveRemote(ctx, u)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// CheckLoop follows a chain of our own short links that starts with shortID
+// redirecting to destination, and reports ErrLoop if it returns to a link
+// already visited or ErrTooManyHops if it is too long. Each link's default
+// destination is followed.
+func (r *Resolver) CheckLoop(ctx context.Context, shortID, destination string) error {
+	seen := map[string]bool{shortID: true}
+
+	for hops := 0; ; hops++ {
+		u, err := urlutil.Normalize(destination)
+		if err != nil {
+			return nil
+		}
+		next, own := r.ownShortID(u)
+		if !own {
+			return nil
+		}
+		if seen[next] {
+			return ErrLoop
+		}
+		if hops >= r.maxHops {
+			return ErrTooManyHops
+		}
+		seen[next] = true
+
+		link, err := r.links.ExpandURL(ctx, next)
+		if err != nil {
+			return err
+		}
+		destination = link.OriginalURL
+	}
+}
+
+// resolveOwn returns the fixed target of one of our own links
+func (r *Resolver) resolveOwn(ctx context.Context, shortID string) (string, error) {
+	link, err := r.links.ExpandURL(ctx, shortID)
+	if err != nil {
+		return "", err
+	}
+	if len(link.Rules) > 0 || len(link.Variants) > 0 || link.PasswordProtected || link.SignatureRequired {
+		return "", ErrUnresolvable
+	}
+	return link.OriginalURL, nil
+}
+
+// resolveRemote asks another shortener where u redirects to without following the redirect
+func (r *Resolver) resolveRemote(ctx context.Context, u *url.URL) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnresolvable, err)
+	}
+	resp.Body.Close()
+
+	location, err := resp.Location()
+	if err != nil || resp.StatusCode < 300 || resp.StatusCode > 399 {
+		return "", fmt.Errorf("%w: %s answered %d without a redirect", ErrUnresolvable, u.Host, resp.StatusCode)
+	}
+	return location.String(), nil
+}
+
+// ownShortID extracts the short ID from one of our short URLs, in either the
+// "/{id}" or the "/v1/expand/{id}" form
+func (r *Resolver) ownShortID(u *url.URL) (string, bool) {
+	if !slices.Contains(r.own, u.Hostname()) {
+		return "", false
+	}
+
+	path := strings.TrimPrefix(u.Path, "/v1/expand")
+	id := strings.TrimSuffix(strings.TrimPrefix(path, "/"), "+")
+	if id == "" || strings.Contains(id, "/") {
+		return "", false
+	}
+	return id, true
+}
+
+func matchesAny(host string, domains []string) bool {
+	for _, domain := range domains {
+		if urlutil.MatchesDomain(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeHosts(hosts []string) []string {
+	normalized := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if h, err := urlutil.NormalizeHost(strings.TrimPrefix(host, ".")); err == nil && h != "" {
+			normalized = append(normalized, h)
+		}
+	}
+	return normalized
+}
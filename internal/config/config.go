@@ -10,31 +10,532 @@ import (
 
 // Config holds application configuration
 type Config struct {
-	Port            int           `mapstructure:"port"`
-	Env             string        `mapstructure:"env"`
-	ServiceName     string        `mapstructure:"service_name"`
-	OTLPEndpoint    string        `mapstructure:"otel_exporter_otlp_endpoint"`
-	TracesEndpoint  string        `mapstructure:"traces_endpoint"`
-	MetricsEndpoint string        `mapstructure:"metrics_endpoint"`
-	UseGrpc         bool          `mapstructure:"use_grpc"`
-	GrpcServerAddr  string        `mapstructure:"grpc_server_addr"`
-	GrpcTimeout     time.Duration `mapstructure:"grpc_timeout"`
+	Port            int               `mapstructure:"port"`
+	Env             string            `mapstructure:"env"`
+	ServiceName     string            `mapstructure:"service_name"`
+	BaseURL         string            `mapstructure:"base_url"` // public scheme and host used to build short URLs
+	OTLPEndpoint    string            `mapstructure:"otel_exporter_otlp_endpoint"`
+	TracesEndpoint  string            `mapstructure:"traces_endpoint"`
+	MetricsEndpoint string            `mapstructure:"metrics_endpoint"`
+	UseGrpc         bool              `mapstructure:"use_grpc"`
+	GrpcServerAddr  string            `mapstructure:"grpc_server_addr"`
+	GrpcTimeout     time.Duration     `mapstructure:"grpc_timeout"`
+	TrustedProxies  []string          `mapstructure:"trusted_proxies"`   // CIDRs allowed to set client IP headers
+	RemoteIPHeaders []string          `mapstructure:"remote_ip_headers"` // headers carrying the client IP, in priority order
+	Classifier      ClassifierConfig  `mapstructure:"classifier"`
+	GeoIP           GeoIPConfig       `mapstructure:"geoip"`
+	LinkCache       LinkCacheConfig   `mapstructure:"link_cache"`
+	ClickStats      ClickStatsConfig  `mapstructure:"click_stats"`
+	Split           SplitConfig       `mapstructure:"split"`
+	QR              QRConfig          `mapstructure:"qr"`
+	Password        PasswordConfig    `mapstructure:"password"`
+	Signing         SigningConfig     `mapstructure:"signing"`
+	Admin           AdminConfig       `mapstructure:"admin"`
+	Pages           PagesConfig       `mapstructure:"pages"`
+	Blocklist       BlocklistConfig   `mapstructure:"blocklist"`
+	Chaining        ChainingConfig    `mapstructure:"chaining"`
+	Redirect        RedirectConfig    `mapstructure:"redirect"`
+	UTM             UTMConfig         `mapstructure:"utm"`
+	Idempotency     IdempotencyConfig `mapstructure:"idempotency"`
+	Webhooks        WebhooksConfig    `mapstructure:"webhooks"`
+	HTTPLogging     HTTPLoggingConfig `mapstructure:"http_logging"`
+	Log             LogConfig         `mapstructure:"log"`
+	AccessLog       AccessLogConfig   `mapstructure:"access_log"`
+	Tracing         TracingConfig     `mapstructure:"tracing"`
+	Metrics         MetricsConfig     `mapstructure:"metrics"`
+}
+
+// ClassifierConfig configures user agent and bot classification of clicks
+type ClassifierConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	RulesPath      string        `mapstructure:"rules_path"`      // empty uses the built-in rules
+	ReloadInterval time.Duration `mapstructure:"reload_interval"` // how often the rules file is checked for changes
+	UnfurlPage     bool          `mapstructure:"unfurl_page"`     // serve a metadata page instead of a redirect to link unfurlers
+}
+
+// GeoIPConfig configures client geolocation from MaxMind-format databases
+type GeoIPConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	DatabasePath    string        `mapstructure:"database_path"`     // country or city database (.mmdb)
+	ASNDatabasePath string        `mapstructure:"asn_database_path"` // optional separate ASN database (.mmdb)
+	ReloadInterval  time.Duration `mapstructure:"reload_interval"`   // how often the database files are checked for changes
+	ForwardMetadata bool          `mapstructure:"forward_metadata"`  // forward the lookup result to the core as gRPC metadata
+}
+
+// LinkCacheConfig configures the in-memory cache of links expanded through the core
+type LinkCacheConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Size    int           `mapstructure:"size"` // maximum number of cached links
+	TTL     time.Duration `mapstructure:"ttl"`  // how long a link and its rules are served from cache
+}
+
+// ClickStatsConfig configures the in-memory click statistics kept per short link
+type ClickStatsConfig struct {
+	Size        int           `mapstructure:"size"`         // most links with statistics, the least recently clicked are dropped
+	TTL         time.Duration `mapstructure:"ttl"`          // statistics of a link not clicked for this long are dropped
+	MaxVisitors int           `mapstructure:"max_visitors"` // unique visitors tracked per link, later new visitors are not counted as unique
+}
+
+// SplitConfig configures sticky assignment of A/B split variants
+type SplitConfig struct {
+	CookieName   string        `mapstructure:"cookie_name"`    // cookie holding the visitor ID for cookie stickiness
+	CookieMaxAge time.Duration `mapstructure:"cookie_max_age"` // lifetime of the visitor cookie
+}
+
+// QRConfig configures QR code generation for short links
+type QRConfig struct {
+	DefaultSize   int           `mapstructure:"default_size"`   // image size in pixels when none is requested
+	MaxSize       int           `mapstructure:"max_size"`       // largest image size a client may request
+	DefaultLevel  string        `mapstructure:"default_level"`  // error correction level: L, M, Q or H
+	DefaultMargin int           `mapstructure:"default_margin"` // quiet zone in modules
+	CacheSize     int           `mapstructure:"cache_size"`     // number of rendered images kept in memory
+	MaxAge        time.Duration `mapstructure:"max_age"`        // Cache-Control max-age of QR responses
+}
+
+// PasswordConfig configures access to password protected links
+type PasswordConfig struct {
+	Header          string        `mapstructure:"header"`           // request header API clients use to send the password
+	CookieSecret    string        `mapstructure:"cookie_secret"`    // HMAC key for unlock cookies, random per process if empty
+	CookieTTL       time.Duration `mapstructure:"cookie_ttl"`       // how long an unlocked link skips the challenge
+	MaxAttempts     int           `mapstructure:"max_attempts"`     // attempts per client and link within attempt_window
+	AttemptWindow   time.Duration `mapstructure:"attempt_window"`   // window for max_attempts
+	LockoutAfter    int           `mapstructure:"lockout_after"`    // consecutive failures before a client is locked out
+	LockoutDuration time.Duration `mapstructure:"lockout_duration"` // how long a locked out client is rejected
+}
+
+// SigningConfig configures signed, expiring short URLs
+type SigningConfig struct {
+	Enabled     bool              `mapstructure:"enabled"`
+	ActiveKeyID string            `mapstructure:"active_key_id"` // key new signatures are made with
+	Keys        map[string]string `mapstructure:"keys"`          // key ID to secret, keep retired keys until their URLs expire
+	Required    bool              `mapstructure:"required"`      // reject unsigned expansions of every link, not just signed-only ones
+	DefaultTTL  time.Duration     `mapstructure:"default_ttl"`   // lifetime of a signed URL when none is requested
+	MaxTTL      time.Duration     `mapstructure:"max_ttl"`       // longest lifetime a client may request
+}
+
+// AdminConfig configures the administrative API
+type AdminConfig struct {
+	Token string `mapstructure:"token"` // bearer token for admin endpoints, empty disables them
+}
+
+// PagesConfig configures the HTML pages served instead of a plain redirect
+type PagesConfig struct {
+	TemplatesDir string             `mapstructure:"templates_dir"` // optional directory whose *.html files replace built-in pages of the same name
+	Theme        ThemeConfig        `mapstructure:"theme"`
+	Preview      bool               `mapstructure:"preview"` // serve a preview page for short IDs with a "+" suffix
+	Interstitial InterstitialConfig `mapstructure:"interstitial"`
+}
+
+// ThemeConfig customises the look of the gateway's HTML pages
+type ThemeConfig struct {
+	BrandName       string `mapstructure:"brand_name"`
+	LogoURL         string `mapstructure:"logo_url"`
+	AccentColor     string `mapstructure:"accent_color"`
+	BackgroundColor string `mapstructure:"background_color"`
+	TextColor       string `mapstructure:"text_color"`
+	StylesheetURL   string `mapstructure:"stylesheet_url"` // extra stylesheet loaded after the built-in styles
+}
+
+// InterstitialConfig configures the warning page shown before leaving to another site
+type InterstitialConfig struct {
+	External        bool          `mapstructure:"external"`         // warn before every destination outside internal_domains
+	InternalDomains []string      `mapstructure:"internal_domains"` // hosts, including their subdomains, that never get a warning
+	Countdown       time.Duration `mapstructure:"countdown"`        // delay before redirecting automatically, 0 waits for a click
+}
+
+// BlocklistConfig configures blocking of malicious destination URLs on shorten and expand
+type BlocklistConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	DomainsPath      string        `mapstructure:"domains_path"`       // hosts blocked exactly, one per line
+	SuffixesPath     string        `mapstructure:"suffixes_path"`      // domains blocked with all their subdomains, e.g. "tk" or "example.net"
+	PatternsPath     string        `mapstructure:"patterns_path"`      // regular expressions matched against the normalised URL
+	ReloadInterval   time.Duration `mapstructure:"reload_interval"`    // how often the files are checked for changes
+	ProtectedDomains []string      `mapstructure:"protected_domains"`  // domains whose IDN or ASCII lookalikes are blocked
+	BlockMixedScript bool          `mapstructure:"block_mixed_script"` // block hosts mixing Latin with Cyrillic, Greek, etc. in one label
+	ExpandAction     string        `mapstructure:"expand_action"`      // "block" serves a block page, "warn" the interstitial warning
+}
+
+// ChainingConfig configures how destinations that are themselves short URLs are handled
+type ChainingConfig struct {
+	Policy          string        `mapstructure:"policy"`           // "reject" refuses short URL destinations, "resolve" replaces them with their final target, "allow" keeps them
+	ShortDomains    []string      `mapstructure:"short_domains"`    // our short domains in addition to the base_url host
+	KnownShorteners []string      `mapstructure:"known_shorteners"` // other URL shorteners, including their subdomains
+	MaxHops         int           `mapstructure:"max_hops"`         // longest chain followed when resolving or checking for loops
+	ResolveTimeout  time.Duration `mapstructure:"resolve_timeout"`  // timeout of each request to another shortener
+}
+
+// RedirectConfig configures the default redirect behaviour, links may override it
+type RedirectConfig struct {
+	Status          int           `mapstructure:"status"`            // 301, 302, 307 or 308
+	Query           string        `mapstructure:"query"`             // forward the short URL's query string: off, keep, override or append
+	Fragment        string        `mapstructure:"fragment"`          // destination or visitor
+	PermanentMaxAge time.Duration `mapstructure:"permanent_max_age"` // how long caches may keep 301 and 308 redirects of links that are the same for every visitor
+}
+
+// UTMConfig configures UTM parameter templating of new links
+type UTMConfig struct {
+	Presets map[string]UTMPreset `mapstructure:"presets"` // campaign presets by name, names are case-insensitive
+}
+
+// UTMPreset holds the utm_* values applied by a campaign preset
+type UTMPreset struct {
+	Source   string `mapstructure:"source"`
+	Medium   string `mapstructure:"medium"`
+	Campaign string `mapstructure:"campaign"`
+	Term     string `mapstructure:"term"`
+	Content  string `mapstructure:"content"`
+}
+
+// WebhooksConfig configures delivery of link events to subscribed HTTP endpoints
+type WebhooksConfig struct {
+	Enabled           bool          `mapstructure:"enabled"`
+	SubscriptionsPath string        `mapstructure:"subscriptions_path"` // JSON file subscriptions are stored in, empty keeps them in memory only
+	DeadLetterPath    string        `mapstructure:"dead_letter_path"`   // JSON lines file of deliveries that were given up
+	Workers           int           `mapstructure:"workers"`            // concurrent deliveries
+	QueueSize         int           `mapstructure:"queue_size"`         // deliveries waiting for a worker, more are dead-lettered
+	MaxAttempts       int           `mapstructure:"max_attempts"`       // attempts before a delivery is dead-lettered
+	InitialBackoff    time.Duration `mapstructure:"initial_backoff"`    // wait before the first retry, doubled for every further retry
+	MaxBackoff        time.Duration `mapstructure:"max_backoff"`
+	Timeout           time.Duration `mapstructure:"timeout"`       // timeout of a single attempt
+	LogSize           int           `mapstructure:"log_size"`      // deliveries kept per subscription for the delivery log
+	MaxScheduled      int           `mapstructure:"max_scheduled"` // link.expired events waiting for their signed URL to expire
+}
+
+// HTTPLoggingConfig configures what the request log and request spans record
+// about requests and responses, and how secrets are masked
+type HTTPLoggingConfig struct {
+	Headers          bool              `mapstructure:"headers"`            // log request and response headers
+	HeaderAllowlist  []string          `mapstructure:"header_allowlist"`   // when set, only these headers are logged unmasked
+	HeaderDenylist   []string          `mapstructure:"header_denylist"`    // headers whose values are masked
+	QueryParams      []string          `mapstructure:"query_params"`       // query and form parameters whose values are masked
+	JSONFields       []string          `mapstructure:"json_fields"`        // JSON body fields masked, "name" at any depth or a dotted path such as "signed.expires_in"
+	Mask             string            `mapstructure:"mask"`               // replacement of masked values
+	Bodies           bool              `mapstructure:"bodies"`             // capture request and response bodies
+	BodyEnvs         []string          `mapstructure:"body_envs"`          // environments bodies are captured in, empty for all
+	SkipBodyRoutes   []string          `mapstructure:"skip_body_routes"`   // route patterns whose bodies are never captured
+	BodyContentTypes []string          `mapstructure:"body_content_types"` // media types, "type/" prefixes or "+suffix"es of captured bodies
+	MaxBodyBytes     int               `mapstructure:"max_body_bytes"`     // bodies are captured up to this many bytes while streaming through, larger ones are marked truncated, 0 or less captures them whole
+	SpanBodies       bool              `mapstructure:"span_bodies"`        // also attach captured bodies to the request span
+	Sampling         LogSamplingConfig `mapstructure:"sampling"`
+}
+
+// TracingConfig configures how spans are sampled and where they are exported
+type TracingConfig struct {
+	Exporter    string              `mapstructure:"exporter"`    // otlp, stdout, file or none
+	Protocol    string              `mapstructure:"protocol"`    // OTLP over http or grpc
+	Endpoint    string              `mapstructure:"endpoint"`    // OTLP host:port, empty uses traces_endpoint
+	Insecure    bool                `mapstructure:"insecure"`    // plain HTTP or gRPC without TLS
+	TLS         TLSConfig           `mapstructure:"tls"`         // used unless insecure is set
+	Headers     map[string]string   `mapstructure:"headers"`     // extra OTLP request headers, such as authentication
+	Compression string              `mapstructure:"compression"` // none or gzip
+	Timeout     time.Duration       `mapstructure:"timeout"`     // time limit of one export
+	FilePath    string              `mapstructure:"file_path"`   // JSON lines file of the file exporter
+	Batch       TraceBatchConfig    `mapstructure:"batch"`
+	Sampling    TraceSamplingConfig `mapstructure:"sampling"`
+}
+
+// TLSConfig configures a TLS client
+type TLSConfig struct {
+	CAFile             string `mapstructure:"ca_file"`              // PEM CA bundle, empty uses the system roots
+	CertFile           string `mapstructure:"cert_file"`            // PEM client certificate for mutual TLS
+	KeyFile            string `mapstructure:"key_file"`             // PEM key of the client certificate
+	ServerName         string `mapstructure:"server_name"`          // overrides the name checked against the server certificate
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"` // do not verify the server certificate, for testing only
+}
+
+// TraceBatchConfig tunes the batching of exported spans
+type TraceBatchConfig struct {
+	MaxQueueSize  int           `mapstructure:"max_queue_size"`  // spans buffered for export, new spans are dropped when full
+	MaxExportSize int           `mapstructure:"max_export_size"` // most spans sent per export
+	Timeout       time.Duration `mapstructure:"timeout"`         // longest time a span waits before it is exported
+}
+
+// TraceSamplingConfig configures which traces are kept
+type TraceSamplingConfig struct {
+	Ratio         float64       `mapstructure:"ratio"`          // fraction of new traces sampled, between 0 and 1
+	ParentBased   bool          `mapstructure:"parent_based"`   // follow the sampling decision of an incoming trace context
+	NeverPaths    []string      `mapstructure:"never_paths"`    // URL path prefixes of traced routes that are never sampled; /metrics and /swagger are not traced at all
+	AlwaysRoutes  []string      `mapstructure:"always_routes"`  // route patterns that are always traced
+	KeepErrors    bool          `mapstructure:"keep_errors"`    // also export unsampled spans that end with an error, which records every span as with a ratio of 1 and only saves the export
+	SlowThreshold time.Duration `mapstructure:"slow_threshold"` // also export unsampled spans that take longer, 0 disables it
+}
+
+// MetricsConfig configures how metrics are recorded and exported
+type MetricsConfig struct {
+	TopHosts         int                `mapstructure:"top_hosts"`           // most frequent domains and destination hosts given their own label, the rest are "other"
+	TopHostsMinCount int                `mapstructure:"top_hosts_min_count"` // times a host must be seen while among the most frequent before it gets its own label
+	TopVariants      int                `mapstructure:"top_variants"`        // most frequent split variant names given their own label, with the same min count and label cap as hosts
+	TopHostsLabels   int                `mapstructure:"top_hosts_labels"`    // distinct hosts, and variant names, ever given a label until restart, later newcomers stay "other" as series never expire
+	Prometheus       bool               `mapstructure:"prometheus"`          // serve /metrics for scraping
+	Listen           string             `mapstructure:"listen"`              // separate address for /metrics, such as :9464; empty serves it on port
+	ExemplarFilter   string             `mapstructure:"exemplar_filter"`     // trace_based, always_on or always_off
+	OTLP             MetricExportConfig `mapstructure:"otlp"`
+}
+
+// MetricExportConfig configures periodic pushing of metrics over OTLP
+type MetricExportConfig struct {
+	Enabled              bool              `mapstructure:"enabled"`
+	Protocol             string            `mapstructure:"protocol"`              // http or grpc
+	Endpoint             string            `mapstructure:"endpoint"`              // host:port, empty uses metrics_endpoint
+	URLPath              string            `mapstructure:"url_path"`              // HTTP path, empty uses /v1/metrics
+	Insecure             bool              `mapstructure:"insecure"`              // plain HTTP or gRPC without TLS
+	TLS                  TLSConfig         `mapstructure:"tls"`                   // used unless insecure is set
+	Headers              map[string]string `mapstructure:"headers"`               // extra request headers, such as authentication
+	Compression          string            `mapstructure:"compression"`           // none or gzip
+	Timeout              time.Duration     `mapstructure:"timeout"`               // time limit of one export
+	Interval             time.Duration     `mapstructure:"interval"`              // time between exports
+	Temporality          string            `mapstructure:"temporality"`           // cumulative, delta or lowmemory
+	HistogramAggregation string            `mapstructure:"histogram_aggregation"` // explicit buckets or base2 exponential
+}
+
+// LogSamplingConfig thins out the request log. Failed and slow requests are always logged.
+type LogSamplingConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Initial       int           `mapstructure:"initial"`        // requests logged every second before sampling starts
+	Thereafter    int           `mapstructure:"thereafter"`     // then every Mth request is logged, 0 logs none
+	SlowThreshold time.Duration `mapstructure:"slow_threshold"` // requests taking longer are always logged
+}
+
+// AccessLogConfig configures a web server style access log, written to its own
+// rotated file independent of the application log
+type AccessLogConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	Format     string `mapstructure:"format"`       // common, combined or json
+	Path       string `mapstructure:"path"`         // file the log is written to
+	MaxSizeMB  int    `mapstructure:"max_size_mb"`  // the file is rotated when it grows beyond this size
+	MaxBackups int    `mapstructure:"max_backups"`  // rotated files kept, 0 keeps all
+	MaxAgeDays int    `mapstructure:"max_age_days"` // days rotated files are kept, 0 keeps them forever
+	Compress   bool   `mapstructure:"compress"`     // gzip rotated files
+}
+
+// LogConfig configures the application log level and per-request debug logging
+type LogConfig struct {
+	Level            string          `mapstructure:"level"`               // debug, info, warn or error, empty for debug in env dev and info otherwise; reapplied on SIGHUP
+	DebugSecret      string          `mapstructure:"debug_secret"`        // HMAC key of debug tokens, empty disables the debug header
+	DebugHeader      string          `mapstructure:"debug_header"`        // request header carrying a debug token
+	DebugTokenMaxTTL time.Duration   `mapstructure:"debug_token_max_ttl"` // longest lifetime of a minted debug token
+	DebugBaggageKey  string          `mapstructure:"debug_baggage_key"`   // baggage member that turns on debug logging when "true" or "1", empty disables it
+	Export           LogExportConfig `mapstructure:"export"`
+}
+
+// LogExportConfig configures exporting log records through OpenTelemetry in
+// addition to writing them to stdout
+type LogExportConfig struct {
+	Exporter     string            `mapstructure:"exporter"`       // otlp, stdout or file, empty disables exporting
+	Endpoint     string            `mapstructure:"endpoint"`       // OTLP HTTP host:port, empty uses otel_exporter_otlp_endpoint
+	Insecure     bool              `mapstructure:"insecure"`       // use plain HTTP for OTLP
+	Headers      map[string]string `mapstructure:"headers"`        // extra OTLP request headers, such as authentication
+	FilePath     string            `mapstructure:"file_path"`      // JSON lines file of the file exporter
+	MaxQueueSize int               `mapstructure:"max_queue_size"` // records buffered for export, the oldest are dropped when full
+	BatchSize    int               `mapstructure:"batch_size"`     // most records sent per export
+	Interval     time.Duration     `mapstructure:"interval"`       // longest time a record waits before it is exported
+	Timeout      time.Duration     `mapstructure:"timeout"`        // time limit of one export
+}
+
+// IdempotencyConfig configures replaying of retried POST requests that carry an idempotency key
+type IdempotencyConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	Header           string        `mapstructure:"header"`             // request header carrying the key
+	TTL              time.Duration `mapstructure:"ttl"`                // how long the first response is replayed
+	MaxKeys          int           `mapstructure:"max_keys"`           // keys kept in memory, requests beyond it are not deduplicated
+	MaxKeyLength     int           `mapstructure:"max_key_length"`     // longer keys are rejected
+	MaxRequestBytes  int64         `mapstructure:"max_request_bytes"`  // larger request bodies carrying a key are rejected with 413
+	MaxResponseBytes int           `mapstructure:"max_response_bytes"` // larger responses are not stored, so retries run again, 0 or less stores any size
 }
 
 // Load loads configuration from config.yaml and environment variables
 func Load() *Config {
+	cfg, err := load(false)
+	if err != nil {
+		log.Fatalf("Error unmarshaling config: %v", err)
+	}
+	return cfg
+}
+
+// Reload loads the configuration again while the gateway is running. Unlike
+// Load it returns an error, instead of falling back to the defaults or exiting,
+// when config.yaml cannot be read or decoded.
+func Reload() (*Config, error) {
+	return load(true)
+}
+
+func load(strict bool) (*Config, error) {
 	v := viper.New()
 
 	// Set default values
 	v.SetDefault("port", 8080)
 	v.SetDefault("env", "development")
 	v.SetDefault("service_name", "api-gateway")
+	v.SetDefault("base_url", "http://localhost:8080")
 	v.SetDefault("otel_exporter_otlp_endpoint", "localhost:4318")
 	v.SetDefault("traces_endpoint", "localhost:4318")
 	v.SetDefault("metrics_endpoint", "localhost:9090")
 	v.SetDefault("use_grpc", true)
 	v.SetDefault("grpc_server_addr", "localhost:50051")
 	v.SetDefault("grpc_timeout", 5*time.Second)
+	v.SetDefault("trusted_proxies", []string{"127.0.0.0/8", "::1/128", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"})
+	v.SetDefault("remote_ip_headers", []string{"X-Forwarded-For", "X-Real-IP"})
+	v.SetDefault("classifier.enabled", true)
+	v.SetDefault("classifier.rules_path", "")
+	v.SetDefault("classifier.reload_interval", 30*time.Second)
+	v.SetDefault("classifier.unfurl_page", false)
+	v.SetDefault("geoip.enabled", false)
+	v.SetDefault("geoip.database_path", "GeoLite2-City.mmdb")
+	v.SetDefault("geoip.asn_database_path", "")
+	v.SetDefault("geoip.reload_interval", time.Minute)
+	v.SetDefault("geoip.forward_metadata", false)
+	v.SetDefault("link_cache.enabled", true)
+	v.SetDefault("link_cache.size", 10000)
+	v.SetDefault("link_cache.ttl", time.Minute)
+	v.SetDefault("click_stats.size", 10000)
+	v.SetDefault("click_stats.ttl", 24*time.Hour)
+	v.SetDefault("click_stats.max_visitors", 1024)
+	v.SetDefault("split.cookie_name", "sl_vid")
+	v.SetDefault("split.cookie_max_age", 30*24*time.Hour)
+	v.SetDefault("qr.default_size", 256)
+	v.SetDefault("qr.max_size", 2048)
+	v.SetDefault("qr.default_level", "M")
+	v.SetDefault("qr.default_margin", 4)
+	v.SetDefault("qr.cache_size", 1000)
+	v.SetDefault("qr.max_age", 24*time.Hour)
+	v.SetDefault("password.header", "X-Link-Password")
+	v.SetDefault("password.cookie_secret", "")
+	v.SetDefault("password.cookie_ttl", time.Hour)
+	v.SetDefault("password.max_attempts", 5)
+	v.SetDefault("password.attempt_window", time.Minute)
+	v.SetDefault("password.lockout_after", 10)
+	v.SetDefault("password.lockout_duration", 15*time.Minute)
+	v.SetDefault("signing.enabled", false)
+	v.SetDefault("signing.active_key_id", "")
+	v.SetDefault("signing.required", false)
+	v.SetDefault("signing.default_ttl", 24*time.Hour)
+	v.SetDefault("signing.max_ttl", 30*24*time.Hour)
+	v.SetDefault("admin.token", "")
+	v.SetDefault("pages.templates_dir", "")
+	v.SetDefault("pages.theme.brand_name", "Shortlink")
+	v.SetDefault("pages.theme.accent_color", "#1a73e8")
+	v.SetDefault("pages.theme.background_color", "#ffffff")
+	v.SetDefault("pages.theme.text_color", "#222222")
+	v.SetDefault("pages.preview", true)
+	v.SetDefault("pages.interstitial.external", false)
+	v.SetDefault("pages.interstitial.internal_domains", []string{})
+	v.SetDefault("pages.interstitial.countdown", 5*time.Second)
+	v.SetDefault("blocklist.enabled", true)
+	v.SetDefault("blocklist.domains_path", "")
+	v.SetDefault("blocklist.suffixes_path", "")
+	v.SetDefault("blocklist.patterns_path", "")
+	v.SetDefault("blocklist.reload_interval", 30*time.Second)
+	v.SetDefault("blocklist.protected_domains", []string{})
+	v.SetDefault("blocklist.block_mixed_script", true)
+	v.SetDefault("blocklist.expand_action", "block")
+	v.SetDefault("chaining.policy", "reject")
+	v.SetDefault("chaining.short_domains", []string{})
+	v.SetDefault("chaining.known_shorteners", []string{"bit.ly", "bitly.com", "tinyurl.com", "t.co", "goo.gl", "ow.ly", "is.gd", "buff.ly", "rebrand.ly", "cutt.ly", "shorturl.at", "t.ly", "rb.gy"})
+	v.SetDefault("chaining.max_hops", 5)
+	v.SetDefault("chaining.resolve_timeout", 5*time.Second)
+	v.SetDefault("redirect.status", 302)
+	v.SetDefault("redirect.query", "off")
+	v.SetDefault("redirect.fragment", "destination")
+	v.SetDefault("redirect.permanent_max_age", time.Hour)
+	v.SetDefault("idempotency.enabled", true)
+	v.SetDefault("idempotency.header", "Idempotency-Key")
+	v.SetDefault("idempotency.ttl", 24*time.Hour)
+	v.SetDefault("idempotency.max_keys", 100000)
+	v.SetDefault("idempotency.max_key_length", 255)
+	v.SetDefault("idempotency.max_request_bytes", 1<<20)
+	v.SetDefault("idempotency.max_response_bytes", 64*1024)
+	v.SetDefault("webhooks.enabled", true)
+	v.SetDefault("webhooks.subscriptions_path", "data/webhooks/subscriptions.json")
+	v.SetDefault("webhooks.dead_letter_path", "data/webhooks/dead_letters.jsonl")
+	v.SetDefault("webhooks.workers", 4)
+	v.SetDefault("webhooks.queue_size", 1000)
+	v.SetDefault("webhooks.max_attempts", 8)
+	v.SetDefault("webhooks.initial_backoff", time.Second)
+	v.SetDefault("webhooks.max_backoff", 10*time.Minute)
+	v.SetDefault("webhooks.timeout", 10*time.Second)
+	v.SetDefault("webhooks.log_size", 200)
+	v.SetDefault("webhooks.max_scheduled", 10000)
+	v.SetDefault("http_logging.headers", true)
+	v.SetDefault("http_logging.header_denylist", []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "AccessToken", "RecaptchaToken", "X-Api-Key", "X-Debug"})
+	v.SetDefault("http_logging.query_params", []string{"sig", "token", "access_token", "password", "api_key"})
+	v.SetDefault("http_logging.json_fields", []string{"password", "secret", "token", "access_token", "refresh_token", "recaptcha_token", "api_key"})
+	v.SetDefault("http_logging.mask", "[REDACTED]")
+	v.SetDefault("http_logging.bodies", true)
+	v.SetDefault("http_logging.body_envs", []string{"local", "dev", "development"})
+	v.SetDefault("http_logging.skip_body_routes", []string{"/v1/links/:shortID/qr"})
+	v.SetDefault("http_logging.body_content_types", []string{"application/json", "+json", "application/x-www-form-urlencoded", "text/plain"})
+	v.SetDefault("http_logging.max_body_bytes", 4096)
+	v.SetDefault("http_logging.span_bodies", true)
+	v.SetDefault("http_logging.sampling.enabled", false)
+	v.SetDefault("http_logging.sampling.initial", 100)
+	v.SetDefault("http_logging.sampling.thereafter", 100)
+	v.SetDefault("http_logging.sampling.slow_threshold", 500*time.Millisecond)
+	v.SetDefault("log.level", "")
+	v.SetDefault("log.debug_secret", "")
+	v.SetDefault("log.debug_header", "X-Debug")
+	v.SetDefault("log.debug_token_max_ttl", time.Hour)
+	v.SetDefault("log.debug_baggage_key", "")
+	v.SetDefault("log.export.exporter", "")
+	v.SetDefault("log.export.endpoint", "")
+	v.SetDefault("log.export.insecure", true)
+	v.SetDefault("log.export.headers", map[string]string{})
+	v.SetDefault("log.export.file_path", "data/logs/otel.jsonl")
+	v.SetDefault("log.export.max_queue_size", 2048)
+	v.SetDefault("log.export.batch_size", 512)
+	v.SetDefault("log.export.interval", time.Second)
+	v.SetDefault("log.export.timeout", 30*time.Second)
+	v.SetDefault("tracing.exporter", "otlp")
+	v.SetDefault("tracing.protocol", "http")
+	v.SetDefault("tracing.endpoint", "")
+	v.SetDefault("tracing.insecure", true)
+	v.SetDefault("tracing.tls.ca_file", "")
+	v.SetDefault("tracing.tls.cert_file", "")
+	v.SetDefault("tracing.tls.key_file", "")
+	v.SetDefault("tracing.tls.server_name", "")
+	v.SetDefault("tracing.tls.insecure_skip_verify", false)
+	v.SetDefault("tracing.headers", map[string]string{})
+	v.SetDefault("tracing.compression", "none")
+	v.SetDefault("tracing.timeout", 10*time.Second)
+	v.SetDefault("tracing.file_path", "data/traces/spans.jsonl")
+	v.SetDefault("tracing.batch.max_queue_size", 2048)
+	v.SetDefault("tracing.batch.max_export_size", 512)
+	v.SetDefault("tracing.batch.timeout", 5*time.Second)
+	v.SetDefault("tracing.sampling.ratio", 1.0)
+	v.SetDefault("tracing.sampling.parent_based", true)
+	v.SetDefault("tracing.sampling.never_paths", []string{})
+	v.SetDefault("tracing.sampling.always_routes", []string{})
+	v.SetDefault("tracing.sampling.keep_errors", true)
+	v.SetDefault("tracing.sampling.slow_threshold", time.Second)
+	v.SetDefault("access_log.enabled", false)
+	v.SetDefault("access_log.format", "combined")
+	v.SetDefault("access_log.path", "data/logs/access.log")
+	v.SetDefault("access_log.max_size_mb", 100)
+	v.SetDefault("access_log.max_backups", 10)
+	v.SetDefault("access_log.max_age_days", 14)
+	v.SetDefault("access_log.compress", true)
+	v.SetDefault("metrics.top_hosts", 50)
+	v.SetDefault("metrics.top_hosts_min_count", 5)
+	v.SetDefault("metrics.top_hosts_labels", 200)
+	v.SetDefault("metrics.top_variants", 20)
+	v.SetDefault("metrics.prometheus", true)
+	v.SetDefault("metrics.listen", "")
+	v.SetDefault("metrics.exemplar_filter", "trace_based")
+	v.SetDefault("metrics.otlp.enabled", false)
+	v.SetDefault("metrics.otlp.protocol", "http")
+	v.SetDefault("metrics.otlp.endpoint", "")
+	v.SetDefault("metrics.otlp.url_path", "")
+	v.SetDefault("metrics.otlp.insecure", true)
+	v.SetDefault("metrics.otlp.tls.ca_file", "")
+	v.SetDefault("metrics.otlp.tls.cert_file", "")
+	v.SetDefault("metrics.otlp.tls.key_file", "")
+	v.SetDefault("metrics.otlp.tls.server_name", "")
+	v.SetDefault("metrics.otlp.tls.insecure_skip_verify", false)
+	v.SetDefault("metrics.otlp.headers", map[string]string{})
+	v.SetDefault("metrics.otlp.compression", "none")
+	v.SetDefault("metrics.otlp.timeout", 10*time.Second)
+	v.SetDefault("metrics.otlp.interval", 15*time.Second)
+	v.SetDefault("metrics.otlp.temporality", "cumulative")
+	v.SetDefault("metrics.otlp.histogram_aggregation", "explicit")
 
 	// Set configuration file
 	v.SetConfigName("config")
@@ -51,14 +552,17 @@ func Load() *Config {
 	// Read configuration file
 	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			if strict {
+				return nil, err
+			}
 			log.Printf("Error reading config file: %v", err)
 		}
 	}
 
 	var cfg Config
 	if err := v.Unmarshal(&cfg); err != nil {
-		log.Fatalf("Error unmarshaling config: %v", err)
+		return nil, err
 	}
 
-	return &cfg
+	return &cfg, nil
 }
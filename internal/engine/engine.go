@@ -1,6 +1,8 @@
 package engine
 
 import (
+	"log"
+
 	"github.com/hohotang/shortlink-gateway/internal/config"
 
 	"github.com/gin-contrib/cors"
@@ -19,6 +21,8 @@ func NewEngine(cfg *config.Config) *gin.Engine {
 		"RecaptchaToken",
 		"AccessToken",
 		"Authorization",
+		"X-Link-Password",
+		"Idempotency-Key",
 		"Content-Type",
 		"Upgrade",
 		"Origin",
@@ -29,9 +33,20 @@ func NewEngine(cfg *config.Config) *gin.Engine {
 		"Access-Control-Request-Method",
 		"Access-Control-Request-Headers",
 	}
+	corsConfig.ExposeHeaders = []string{"Idempotent-Replayed"}
 
 	// 初始化 Gin 引擎
 	server := gin.New()
+
+	// 只信任設定中的 proxy 所帶的 client IP header，避免 ClientIP 被偽造
+	if err := server.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Printf("Invalid trusted proxies %v: %v, trusting no proxies", cfg.TrustedProxies, err)
+		_ = server.SetTrustedProxies(nil)
+	}
+	if len(cfg.RemoteIPHeaders) > 0 {
+		server.RemoteIPHeaders = cfg.RemoteIPHeaders
+	}
+
 	server.Use(cors.New(corsConfig))
 	server.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPaths([]string{"/metrics"})))
 
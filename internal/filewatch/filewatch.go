@@ -0,0 +1,81 @@
+package filewatch
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Watcher polls a file and invokes a callback whenever its modification time or size changes.
+// Polling is used instead of inotify so that atomically replaced files (e.g. ConfigMap
+// symlink swaps or `mv new old`) are picked up reliably.
+type Watcher struct {
+	path     string
+	interval time.Duration
+	onChange func()
+
+	modTime time.Time
+	size    int64
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// New starts watching path, calling onChange from a background goroutine when the file changes.
+// A non-positive interval disables polling and returns a watcher that only needs closing.
+func New(path string, interval time.Duration, onChange func()) *Watcher {
+	w := &Watcher{
+		path:     path,
+		interval: interval,
+		onChange: onChange,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		w.modTime = info.ModTime()
+		w.size = info.Size()
+	}
+
+	if interval <= 0 {
+		close(w.done)
+		return w
+	}
+
+	go w.run()
+	return w
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(w.path)
+			if err != nil {
+				// Keep the last known state, the file may be in the middle of being replaced
+				continue
+			}
+			if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+				continue
+			}
+			w.modTime = info.ModTime()
+			w.size = info.Size()
+			w.onChange()
+		}
+	}
+}
+
+// Close stops the watcher and waits for the polling goroutine to exit
+func (w *Watcher) Close() error {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+	return nil
+}
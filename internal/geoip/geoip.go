@@ -0,0 +1,68 @@
+package geoip
+
+import (
+	"context"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Location is the geolocation of a client IP address
+type Location struct {
+	Country string `json:"country,omitempty"` // ISO 3166-1 alpha-2 code
+	Region  string `json:"region,omitempty"`  // ISO 3166-2 subdivision code, without the country prefix
+	City    string `json:"city,omitempty"`
+	ASN     uint   `json:"asn,omitempty"`
+	ASOrg   string `json:"as_org,omitempty"`
+}
+
+// Found reports whether any geolocation data was resolved
+func (l Location) Found() bool {
+	return l.Country != "" || l.ASN != 0
+}
+
+// Attributes returns the location as OpenTelemetry attributes for spans
+func (l Location) Attributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("geo.country.iso_code", l.Country),
+		attribute.String("geo.region.iso_code", l.Region),
+	}
+	if l.City != "" {
+		attrs = append(attrs, attribute.String("geo.locality.name", l.City))
+	}
+	if l.ASN != 0 {
+		attrs = append(attrs,
+			attribute.Int("geo.asn", int(l.ASN)),
+			attribute.String("geo.as_org", l.ASOrg),
+		)
+	}
+	return attrs
+}
+
+// Metadata returns the location as key/value pairs suitable for forwarding to the core
+func (l Location) Metadata() map[string]string {
+	md := make(map[string]string, 3)
+	if l.Country != "" {
+		md["x-client-country"] = l.Country
+	}
+	if l.Region != "" {
+		md["x-client-region"] = l.Region
+	}
+	if l.ASN != 0 {
+		md["x-client-asn"] = strconv.FormatUint(uint64(l.ASN), 10)
+	}
+	return md
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the client location
+func NewContext(ctx context.Context, loc Location) context.Context {
+	return context.WithValue(ctx, contextKey{}, loc)
+}
+
+// FromContext returns the client location stored in ctx, if any
+func FromContext(ctx context.Context) (Location, bool) {
+	loc, ok := ctx.Value(contextKey{}).(Location)
+	return loc, ok
+}
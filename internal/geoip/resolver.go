@@ -0,0 +1,147 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/hohotang/shortlink-gateway/internal/filewatch"
+
+	"github.com/oschwald/maxminddb-golang"
+	"go.uber.org/zap"
+)
+
+// record covers the fields used from the GeoIP2/GeoLite2 Country, City and ASN databases
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	ASN   uint   `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+// database is a hot-reloadable MaxMind database file
+type database struct {
+	path    string
+	reader  atomic.Pointer[maxminddb.Reader]
+	watcher *filewatch.Watcher
+	logger  *zap.Logger
+}
+
+func openDatabase(path string, reloadInterval time.Duration, logger *zap.Logger) (*database, error) {
+	db := &database{path: path, logger: logger}
+	if err := db.load(); err != nil {
+		return nil, err
+	}
+	db.watcher = filewatch.New(path, reloadInterval, db.reload)
+	return db, nil
+}
+
+// load reads the whole file into memory so that a reload never unmaps data still in use by a lookup
+func (db *database) load() error {
+	data, err := os.ReadFile(db.path)
+	if err != nil {
+		return fmt.Errorf("read geoip database: %w", err)
+	}
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return fmt.Errorf("open geoip database %s: %w", db.path, err)
+	}
+	db.reader.Store(reader)
+	return nil
+}
+
+func (db *database) reload() {
+	if err := db.load(); err != nil {
+		db.logger.Error("Failed to reload geoip database, keeping previous version",
+			zap.String("path", db.path),
+			zap.Error(err),
+		)
+		return
+	}
+	db.logger.Info("Reloaded geoip database", zap.String("path", db.path))
+}
+
+func (db *database) lookup(ip net.IP, rec *record) error {
+	return db.reader.Load().Lookup(ip, rec)
+}
+
+func (db *database) close() error {
+	return db.watcher.Close()
+}
+
+// Resolver looks up client IP addresses in local MaxMind-format databases
+type Resolver struct {
+	location *database
+	asn      *database
+}
+
+// NewResolver opens the location database at path and, if asnPath is set, a separate ASN
+// database. Both files are reloaded when they change on disk.
+func NewResolver(path, asnPath string, reloadInterval time.Duration, logger *zap.Logger) (*Resolver, error) {
+	location, err := openDatabase(path, reloadInterval, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Resolver{location: location}
+
+	if asnPath != "" {
+		if r.asn, err = openDatabase(asnPath, reloadInterval, logger); err != nil {
+			_ = location.close()
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Lookup resolves the location of an IP address. Unknown or invalid addresses return an empty Location.
+func (r *Resolver) Lookup(ipAddr string) (Location, error) {
+	ip := net.ParseIP(ipAddr)
+	if ip == nil {
+		return Location{}, fmt.Errorf("invalid ip address %q", ipAddr)
+	}
+
+	var rec record
+	if err := r.location.lookup(ip, &rec); err != nil {
+		return Location{}, err
+	}
+	if r.asn != nil {
+		var asnRec record
+		if err := r.asn.lookup(ip, &asnRec); err != nil {
+			return Location{}, err
+		}
+		rec.ASN, rec.ASOrg = asnRec.ASN, asnRec.ASOrg
+	}
+
+	loc := Location{
+		Country: rec.Country.ISOCode,
+		City:    rec.City.Names["en"],
+		ASN:     rec.ASN,
+		ASOrg:   rec.ASOrg,
+	}
+	if len(rec.Subdivisions) > 0 {
+		loc.Region = rec.Subdivisions[0].ISOCode
+	}
+	return loc, nil
+}
+
+// Close stops watching the database files
+func (r *Resolver) Close() error {
+	err := r.location.close()
+	if r.asn != nil {
+		if asnErr := r.asn.close(); err == nil {
+			err = asnErr
+		}
+	}
+	return err
+}
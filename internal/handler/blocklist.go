@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hohotang/shortlink-gateway/internal/blocklist"
+	"github.com/hohotang/shortlink-gateway/internal/middleware"
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/otel"
+	"github.com/hohotang/shortlink-gateway/internal/page"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// checkDestinations checks every destination of a new link against the blocklist.
+// When one is blocked it writes a 400 response with the reason code and returns false.
+func (h *ShortlinkHandler) checkDestinations(c *gin.Context, req *model.ShortenRequest) bool {
+	if h.Blocklist == nil {
+		return true
+	}
+
+	fields, destinations := destinationFields(req)
+	for i, destination := range destinations {
+		verdict := h.checkBlocklist(c, blocklist.StageShorten, *destination)
+		if !verdict.Blocked() {
+			continue
+		}
+
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Destination URL is blocked",
+			"reason": verdict.Reason,
+			"field":  fields[i],
+		})
+		return false
+	}
+	return true
+}
+
+// checkBlocklist checks a destination, tagging the span and logging blocked URLs
+func (h *ShortlinkHandler) checkBlocklist(c *gin.Context, stage blocklist.Stage, destination string) blocklist.Verdict {
+	ctx := c.Request.Context()
+	verdict := h.Blocklist.Check(ctx, stage, destination)
+	if !verdict.Blocked() {
+		return verdict
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("blocklist.reason", string(verdict.Reason)),
+		attribute.String("blocklist.rule", verdict.Rule),
+	)
+	middleware.GetLogger(ctx).Warn("Blocked destination URL",
+		zap.String("stage", string(stage)),
+		zap.String("url", destination),
+		zap.String("reason", string(verdict.Reason)),
+		zap.String("rule", verdict.Rule),
+	)
+	return verdict
+}
+
+// serveBlocked renders the page shown instead of redirecting to a blocked destination
+func (h *ShortlinkHandler) serveBlocked(c *gin.Context, destination string, verdict blocklist.Verdict) {
+	c.Set(redirectResultKey, otel.RedirectBlocked)
+
+	var host string
+	if u, err := url.Parse(destination); err == nil {
+		host = u.Host
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.HTML(http.StatusForbidden, "blocked.html", page.BlockedData{
+		Theme:  h.theme(),
+		Host:   host,
+		Reason: string(verdict.Reason),
+	})
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hohotang/shortlink-gateway/internal/chain"
+	"github.com/hohotang/shortlink-gateway/internal/middleware"
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"go.uber.org/zap"
+)
+
+// Chaining policies for destinations that are themselves short URLs
+const (
+	chainingReject  = "reject"
+	chainingResolve = "resolve"
+	chainingAllow   = "allow"
+)
+
+// checkChaining applies the chaining policy to every destination of a new link. Under
+// the resolve policy short URL destinations are replaced with their final target.
+// When a destination is refused it writes a 400 response with the reason and returns false.
+func (h *ShortlinkHandler) checkChaining(c *gin.Context, req *model.ShortenRequest) bool {
+	policy := h.Config.Chaining.Policy
+	if h.Chain == nil || policy == chainingAllow {
+		return true
+	}
+
+	ctx := c.Request.Context()
+	fields, destinations := destinationFields(req)
+	for i, destination := range destinations {
+		kind := h.Chain.Classify(*destination)
+		if kind == chain.KindNone {
+			continue
+		}
+
+		if policy != chainingResolve {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Destination URL is a short URL",
+				"reason": kind,
+				"field":  fields[i],
+			})
+			return false
+		}
+
+		resolved, err := h.Chain.Resolve(ctx, *destination)
+		if err != nil {
+			middleware.GetLogger(ctx).Info("Failed to resolve short URL destination",
+				zap.String("url", *destination),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Short URL destination could not be resolved",
+				"reason": kind,
+				"field":  fields[i],
+			})
+			return false
+		}
+		*destination = resolved
+	}
+	return true
+}
+
+// checkLoop guards against chains of our own short links that loop back, for
+// example after a destination was edited. It writes a 508 response and returns
+// false when the chain loops or is too long.
+func (h *ShortlinkHandler) checkLoop(c *gin.Context, shortID, destination string) bool {
+	if h.Chain == nil {
+		return true
+	}
+
+	ctx := c.Request.Context()
+	err := h.Chain.CheckLoop(ctx, shortID, destination)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, chain.ErrLoop), errors.Is(err, chain.ErrTooManyHops):
+		middleware.GetLogger(ctx).Warn("Redirect loop detected",
+			zap.String("short_id", shortID),
+			zap.String("destination", destination),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusLoopDetected, gin.H{"error": "Redirect loop detected"})
+		return false
+	default:
+		// The chain could not be followed, let the next hop deal with it
+		middleware.GetLogger(ctx).Debug("Failed to check redirect chain", zap.Error(err))
+		return true
+	}
+}
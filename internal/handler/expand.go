@@ -2,31 +2,84 @@ package handler
 
 import (
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hohotang/shortlink-gateway/internal/analytics"
+	"github.com/hohotang/shortlink-gateway/internal/blocklist"
+	"github.com/hohotang/shortlink-gateway/internal/geoip"
 	"github.com/hohotang/shortlink-gateway/internal/middleware"
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/otel"
+	"github.com/hohotang/shortlink-gateway/internal/page"
+	"github.com/hohotang/shortlink-gateway/internal/service"
+	"github.com/hohotang/shortlink-gateway/internal/targeting"
+	"github.com/hohotang/shortlink-gateway/internal/useragent"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 // Expand handles URL expansion requests
 // @Summary      Expand a short URL
-// @Description  Redirects to the original URL from a short URL ID
+// @Description  Redirects to the original URL from a short URL ID.
+// @Description  Targeting rules stored with the link may pick a different destination by country, device OS, language or time.
+// @Description  Links with split variants send each visitor to a weighted destination, optionally kept sticky via a cookie.
+// @Description  Known link unfurlers may receive an HTML metadata page instead of a redirect.
+// @Description  Password protected links serve a challenge page unless a valid unlock cookie or the X-Link-Password header is sent.
+// @Description  Signed URLs carry exp, kid and sig query parameters which are verified before the link is looked up (see POST /v1/links/{shortID}/sign).
+// @Description  Links created as signed-only reject unsigned requests.
+// @Description  A "+" suffix on the short ID (e.g. abc123+) shows a preview page with the destination, creation date and click count instead of redirecting.
+// @Description  Links flagged with interstitial, and external destinations when configured, show a warning page that optionally redirects after a countdown.
+// @Description  Destinations that have since been blocklisted get a block page (or the warning page, depending on configuration).
+// @Description  Chains of short links that loop back on themselves are answered with 508 Loop Detected.
+// @Description  The redirect status (301, 302, 307 or 308) and whether the query string is forwarded to the destination are configurable globally and per link.
+// @Description  Permanent redirects of links that are the same for every visitor are cacheable; all other redirects are sent with Cache-Control: private, no-store.
 // @Tags         urls
 // @Produce      html
-// @Param        shortID  path      string  true  "Short URL ID"
+// @Param        shortID          path      string  true   "Short URL ID"
+// @Param        X-Link-Password  header    string  false  "Password of a protected link"
+// @Param        exp              query     int     false  "Expiry of a signed URL as a Unix timestamp"
+// @Param        kid              query     string  false  "ID of the key a signed URL was signed with"
+// @Param        sig              query     string  false  "HMAC-SHA256 signature of a signed URL, base64url encoded"
+// @Success      200      {string}  string  "Metadata page for link unfurlers, preview page or interstitial warning page"
+// @Success      301      {string}  string  "Permanent redirect to original URL"
 // @Success      302      {string}  string  "Redirect to original URL"
+// @Success      307      {string}  string  "Temporary redirect to original URL"
+// @Success      308      {string}  string  "Permanent redirect to original URL"
 // @Failure      400      {object}  map[string]string  "Bad Request"
+// @Failure      401      {string}  string  "Password challenge page"
+// @Failure      403      {object}  map[string]string  "Signature invalid or required, or blocked destination page"
+// @Failure      404      {object}  map[string]string  "Short URL not found"
+// @Failure      410      {object}  map[string]string  "Signed URL expired"
+// @Failure      429      {object}  map[string]string  "Too many password attempts"
 // @Failure      500      {object}  map[string]string  "Internal Server Error"
+// @Failure      508      {object}  map[string]string  "Redirect loop detected"
 // @Router       /v1/expand/{shortID} [get]
 func (h *ShortlinkHandler) Expand(c *gin.Context) {
-	shortID := c.Param("shortID")
+	var destination string
+	defer func() { h.recordRedirect(c, destination) }()
+
+	shortID, preview := h.previewID(c.Param("shortID"))
 	if shortID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing short ID"})
 		return
 	}
 
+	signed, ok := h.checkSignature(c, shortID)
+	if !ok {
+		return
+	}
+
+	client := h.classifyClient(c)
+	location := h.locateClient(c)
+
 	// Call the injected URL service with request context
-	originalURL, err := h.URLService.ExpandURL(c.Request.Context(), shortID)
+	link, err := h.URLService.ExpandURL(c.Request.Context(), shortID)
+	if service.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Short URL not found"})
+		return
+	}
 	if err != nil {
 		logger := middleware.GetLogger(c.Request.Context())
 		logger.Error("Failed to expand URL", zap.Error(err))
@@ -34,5 +87,180 @@ func (h *ShortlinkHandler) Expand(c *gin.Context) {
 		return
 	}
 
-	c.Redirect(http.StatusFound, originalURL)
+	if link.SignatureRequired && !signed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Signed URL required"})
+		return
+	}
+
+	if !h.checkPassword(c, link) {
+		return
+	}
+
+	if preview {
+		h.servePreview(c, link)
+		return
+	}
+
+	decision := h.selectDestination(c, link, client, location)
+
+	if h.Blocklist != nil {
+		if verdict := h.checkBlocklist(c, blocklist.StageExpand, decision.DestinationURL); verdict.Blocked() {
+			if h.Config.Blocklist.ExpandAction == "warn" {
+				c.Set(redirectResultKey, otel.RedirectBlocked)
+				h.serveInterstitial(c, decision.DestinationURL, interstitialFlagged)
+			} else {
+				h.serveBlocked(c, decision.DestinationURL, verdict)
+			}
+			return
+		}
+	}
+
+	if !h.checkLoop(c, shortID, decision.DestinationURL) {
+		return
+	}
+
+	h.recordClick(c, analytics.ClickEvent{
+		ShortID:  shortID,
+		Client:   client,
+		Location: location,
+		Variant:  decision.Variant,
+	})
+
+	if client.IsUnfurler() && h.Config.Classifier.UnfurlPage {
+		h.serveUnfurlPage(c, shortID, decision.DestinationURL)
+		return
+	}
+
+	destination = decision.DestinationURL
+	target := h.location(c, link, decision.DestinationURL)
+
+	if reason := h.interstitialReason(link, decision.DestinationURL); reason != "" {
+		h.serveInterstitial(c, target, reason)
+		return
+	}
+
+	h.redirect(c, link, target)
+}
+
+// redirectResultKey overrides the result recordRedirect derives from the response status
+const redirectResultKey = "redirect_result"
+
+// recordRedirect counts the request by its result, derived from the response
+// status unless a handler stored one under redirectResultKey
+func (h *ShortlinkHandler) recordRedirect(c *gin.Context, destination string) {
+	if h.Metrics == nil {
+		return
+	}
+
+	result := c.GetString(redirectResultKey)
+	if result == "" {
+		switch status := c.Writer.Status(); {
+		case status < http.StatusBadRequest:
+			result = otel.RedirectHit
+		case status == http.StatusNotFound:
+			result = otel.RedirectNotFound
+		case status == http.StatusGone:
+			result = otel.RedirectExpired
+		case status == http.StatusUnauthorized, status == http.StatusForbidden, status == http.StatusTooManyRequests:
+			result = otel.RedirectDenied
+		case status == http.StatusLoopDetected:
+			result = otel.RedirectLoop
+		case status < http.StatusInternalServerError:
+			result = otel.RedirectInvalid
+		default:
+			result = otel.RedirectError
+		}
+	}
+	h.Metrics.Redirect(c.Request.Context(), result, destination)
+}
+
+// selectDestination evaluates the link's targeting rules and split variants for this
+// visitor and records the decision on the span
+func (h *ShortlinkHandler) selectDestination(c *gin.Context, link *model.Link, client useragent.Info, location geoip.Location) targeting.Decision {
+	if len(link.Rules) == 0 && len(link.Variants) == 0 {
+		return targeting.Decision{DestinationURL: link.OriginalURL, RuleIndex: targeting.NoMatch}
+	}
+
+	key, newCookie := h.visitorKey(c, link)
+	decision := targeting.Evaluate(link, targeting.Visitor{
+		Country:   location.Country,
+		OS:        client.OS,
+		Languages: targeting.ParseAcceptLanguage(c.GetHeader("Accept-Language")),
+		Time:      time.Now(),
+		Key:       key,
+	})
+
+	// Only human visitors that were assigned a variant get the sticky cookie
+	if newCookie != "" && decision.Variant != "" && !client.Bot {
+		h.setVisitorCookie(c, newCookie)
+	}
+
+	span := trace.SpanFromContext(c.Request.Context())
+	span.SetAttributes(decision.Attributes()...)
+	span.AddEvent("redirect.targeting", trace.WithAttributes(decision.Attributes()...))
+
+	return decision
+}
+
+// classifyClient classifies the caller's user agent and tags the request span with the result
+func (h *ShortlinkHandler) classifyClient(c *gin.Context) useragent.Info {
+	if h.Classifier == nil {
+		return useragent.Info{Device: useragent.DeviceUnknown}
+	}
+
+	client := h.Classifier.Classify(c.Request.UserAgent())
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(client.Attributes()...)
+	return client
+}
+
+// locateClient resolves the caller's location, tags the request span with it and
+// stores it in the request context so it can be forwarded to the core
+func (h *ShortlinkHandler) locateClient(c *gin.Context) geoip.Location {
+	if h.GeoIP == nil {
+		return geoip.Location{}
+	}
+
+	location, err := h.GeoIP.Lookup(c.ClientIP())
+	if err != nil {
+		middleware.GetLogger(c.Request.Context()).Debug("GeoIP lookup failed", zap.Error(err))
+		return geoip.Location{}
+	}
+
+	ctx := c.Request.Context()
+	trace.SpanFromContext(ctx).SetAttributes(location.Attributes()...)
+	c.Request = c.Request.WithContext(geoip.NewContext(ctx, location))
+	return location
+}
+
+// recordClick completes the click event with request details and stores it for analytics
+func (h *ShortlinkHandler) recordClick(c *gin.Context, event analytics.ClickEvent) {
+	if h.Clicks == nil {
+		return
+	}
+
+	event.Time = time.Now()
+	event.ClientIP = c.ClientIP()
+	event.UserAgent = c.Request.UserAgent()
+	event.Referer = c.Request.Referer()
+	event.Prefetch = useragent.IsPrefetch(c.Request.Header)
+
+	stats := h.Clicks.Record(c.Request.Context(), event)
+	if event.Human() {
+		h.publishClicks(event.ShortID, stats.Clicks-stats.BotClicks)
+	}
+}
+
+// serveUnfurlPage renders Open Graph metadata for the destination so previews work without following the redirect
+func (h *ShortlinkHandler) serveUnfurlPage(c *gin.Context, shortID, destination string) {
+	host := destination
+	if u, err := url.Parse(destination); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.HTML(http.StatusOK, "unfurl.html", page.UnfurlData{
+		ShortURL:       h.shortURL(shortID),
+		DestinationURL: destination,
+		Host:           host,
+	})
 }
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/page"
+)
+
+// Reasons for showing the interstitial page
+const (
+	interstitialFlagged  = "flagged"
+	interstitialExternal = "external"
+)
+
+// interstitialReason returns why visitors should be warned before being sent to
+// destination, or "" when they can be redirected straight away
+func (h *ShortlinkHandler) interstitialReason(link *model.Link, destination string) string {
+	if link.Interstitial {
+		return interstitialFlagged
+	}
+	if h.Config.Pages.Interstitial.External && !h.isInternalURL(destination) {
+		return interstitialExternal
+	}
+	return ""
+}
+
+// isInternalURL reports whether raw points at the gateway itself or one of the
+// configured internal domains
+func (h *ShortlinkHandler) isInternalURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+
+	domains := h.Config.Pages.Interstitial.InternalDomains
+	if base, err := url.Parse(h.Config.BaseURL); err == nil {
+		domains = append([]string{base.Hostname()}, domains...)
+	}
+
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if domain != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// serveInterstitial renders the warning page in place of the redirect. Flagged
+// links never redirect automatically.
+func (h *ShortlinkHandler) serveInterstitial(c *gin.Context, destination, reason string) {
+	host := destination
+	if u, err := url.Parse(destination); err == nil {
+		host = u.Host
+	}
+
+	countdown := 0
+	if reason != interstitialFlagged {
+		countdown = int(h.Config.Pages.Interstitial.Countdown.Seconds())
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.HTML(http.StatusOK, "interstitial.html", page.InterstitialData{
+		Theme:          h.theme(),
+		DestinationURL: destination,
+		Host:           host,
+		Reason:         reason,
+		Countdown:      countdown,
+	})
+}
+
+// theme returns the configured page theme
+func (h *ShortlinkHandler) theme() page.Theme {
+	t := h.Config.Pages.Theme
+	return page.Theme{
+		BrandName:       t.BrandName,
+		LogoURL:         t.LogoURL,
+		AccentColor:     t.AccentColor,
+		BackgroundColor: t.BackgroundColor,
+		TextColor:       t.TextColor,
+		StylesheetURL:   t.StylesheetURL,
+	}
+}
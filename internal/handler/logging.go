@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hohotang/shortlink-gateway/internal/logger"
+	"github.com/hohotang/shortlink-gateway/internal/middleware"
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"go.uber.org/zap"
+)
+
+// GetLogLevel handles requests for the current log level
+// @Summary      Get the log level
+// @Description  Returns the level of the gateway logger.
+// @Tags         admin
+// @Produce      json
+// @Security     AdminToken
+// @Success      200  {object}  map[string]string  "Returns the level"
+// @Failure      401  {object}  map[string]string  "Unauthorized"
+// @Failure      403  {object}  map[string]string  "Admin API is disabled"
+// @Router       /v1/admin/log-level [get]
+func (h *ShortlinkHandler) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"level": logger.Level().Level().String()})
+}
+
+// SetLogLevel handles requests to change the log level without a restart
+// @Summary      Change the log level
+// @Description  Changes the level of the gateway logger until the next restart or SIGHUP, which reapplies log.level from the configuration.
+// @Description  The level is one of debug, info, warn or error.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     AdminToken
+// @Param        request  body      model.LogLevelRequest  true  "New level"
+// @Success      200      {object}  map[string]string  "Returns the new level"
+// @Failure      400      {object}  map[string]string  "Bad Request, or an unknown level"
+// @Failure      401      {object}  map[string]string  "Unauthorized"
+// @Failure      403      {object}  map[string]string  "Admin API is disabled"
+// @Router       /v1/admin/log-level [put]
+func (h *ShortlinkHandler) SetLogLevel(c *gin.Context) {
+	var req model.LogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	level, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown log level %q", req.Level)})
+		return
+	}
+
+	previous := logger.Level().Level()
+	logger.Level().SetLevel(level)
+	middleware.GetLogger(c.Request.Context()).Warn("Log level changed",
+		zap.String("from", previous.String()),
+		zap.String("to", level.String()),
+	)
+
+	c.JSON(http.StatusOK, gin.H{"level": level.String()})
+}
+
+// MintDebugToken handles requests for a debug token
+// @Summary      Mint a debug token
+// @Description  Returns a token that turns on debug logging for every request carrying it in the debug header (X-Debug by default), whatever the log level, until it expires.
+// @Description  token = exp + "." + hex(HMAC-SHA256(log.debug_secret, "debug." + exp)) with exp as a Unix timestamp.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     AdminToken
+// @Param        request  body      model.DebugTokenRequest  false  "Lifetime of the token, 15m by default"
+// @Success      200      {object}  map[string]string  "Returns the header name, the token and its expiry"
+// @Failure      400      {object}  map[string]string  "Bad Request, or debug tokens are not enabled"
+// @Failure      401      {object}  map[string]string  "Unauthorized"
+// @Failure      403      {object}  map[string]string  "Admin API is disabled"
+// @Router       /v1/admin/debug-token [post]
+func (h *ShortlinkHandler) MintDebugToken(c *gin.Context) {
+	cfg := h.Config.Log
+	if cfg.DebugSecret == "" || cfg.DebugHeader == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debug tokens are not enabled"})
+		return
+	}
+
+	var req model.DebugTokenRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+
+	ttl := min(15*time.Minute, cfg.DebugTokenMaxTTL)
+	if req.ExpiresIn != "" {
+		var err error
+		ttl, err = time.ParseDuration(req.ExpiresIn)
+		if err != nil || ttl <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in must be a positive duration such as 15m"})
+			return
+		}
+	}
+	if ttl > cfg.DebugTokenMaxTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("expires_in must not exceed %s", cfg.DebugTokenMaxTTL)})
+		return
+	}
+
+	expires := time.Now().Add(ttl)
+	c.JSON(http.StatusOK, gin.H{
+		"header":     cfg.DebugHeader,
+		"token":      logger.DebugToken([]byte(cfg.DebugSecret), expires),
+		"expires_at": expires.UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hohotang/shortlink-gateway/internal/middleware"
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/page"
+	"go.uber.org/zap"
+)
+
+const unlockCookieName = "sl_unlock"
+
+// Unlock handles password submissions from the challenge page of a protected link
+// @Summary      Unlock a password protected short URL
+// @Description  Verifies the password posted from the challenge page. On success a short-lived signed cookie is set and the client is sent back to the short URL.
+// @Description  Attempts are rate limited per client and link, and repeated failures lock the client out temporarily.
+// @Tags         urls
+// @Accept       x-www-form-urlencoded
+// @Produce      html
+// @Param        shortID   path      string  true  "Short URL ID"
+// @Param        password  formData  string  true  "Link password"
+// @Success      303       {string}  string  "Redirect back to the short URL"
+// @Failure      401       {string}  string  "Challenge page with an error"
+// @Failure      429       {string}  string  "Too many attempts"
+// @Router       /v1/expand/{shortID} [post]
+func (h *ShortlinkHandler) Unlock(c *gin.Context) {
+	shortID, _ := h.previewID(c.Param("shortID"))
+	if shortID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing short ID"})
+		return
+	}
+
+	status := h.verifyPassword(c, shortID, c.PostForm("password"))
+	switch status {
+	case http.StatusOK:
+		h.setUnlockCookie(c, shortID)
+		c.Redirect(http.StatusSeeOther, c.Request.URL.RequestURI())
+	case http.StatusTooManyRequests:
+		h.serveChallenge(c, status, "Too many attempts, please try again later.")
+	case http.StatusUnauthorized:
+		h.serveChallenge(c, status, "Incorrect password.")
+	default:
+		h.serveChallenge(c, status, "Something went wrong, please try again.")
+	}
+}
+
+// checkPassword reports whether the request may be redirected to the destination of the link.
+// For protected links without a valid unlock cookie or password header it writes the response itself.
+func (h *ShortlinkHandler) checkPassword(c *gin.Context, link *model.Link) bool {
+	if !link.PasswordProtected || h.validUnlockCookie(c, link.ShortID) {
+		return true
+	}
+
+	// API clients can supply the password directly instead of going through the challenge page
+	if password := c.GetHeader(h.Config.Password.Header); password != "" {
+		switch status := h.verifyPassword(c, link.ShortID, password); status {
+		case http.StatusOK:
+			return true
+		case http.StatusTooManyRequests:
+			c.JSON(status, gin.H{"error": "Too many password attempts"})
+		case http.StatusUnauthorized:
+			c.JSON(status, gin.H{"error": "Invalid password"})
+		default:
+			c.JSON(status, gin.H{"error": "Failed to verify password"})
+		}
+		return false
+	}
+
+	h.serveChallenge(c, http.StatusUnauthorized, "")
+	return false
+}
+
+// verifyPassword checks a password attempt under rate limiting and lockout, returning
+// 200 on success, 401 for a wrong password, 429 when locked out and 500 on errors
+func (h *ShortlinkHandler) verifyPassword(c *gin.Context, shortID, password string) int {
+	key := c.ClientIP() + "|" + shortID
+
+	if allowed, retryAfter := h.PasswordGuard.Allow(key); !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		return http.StatusTooManyRequests
+	}
+
+	valid, err := h.URLService.VerifyPassword(c.Request.Context(), shortID, password)
+	if err != nil {
+		logger := middleware.GetLogger(c.Request.Context())
+		logger.Error("Failed to verify link password", zap.Error(err))
+		return http.StatusInternalServerError
+	}
+
+	if !valid {
+		h.PasswordGuard.Failure(key)
+		return http.StatusUnauthorized
+	}
+
+	h.PasswordGuard.Success(key)
+	return http.StatusOK
+}
+
+// serveChallenge renders the password form for the current short URL
+func (h *ShortlinkHandler) serveChallenge(c *gin.Context, status int, message string) {
+	c.Header("Cache-Control", "no-store")
+	c.HTML(status, "password.html", page.PasswordData{
+		Theme:  h.theme(),
+		Action: c.Request.URL.RequestURI(),
+		Error:  message,
+		Locked: status == http.StatusTooManyRequests,
+	})
+}
+
+// setUnlockCookie issues a signed cookie that lets the client skip the challenge until it expires.
+// The cookie is scoped to the short URL path so it is only sent for this link.
+func (h *ShortlinkHandler) setUnlockCookie(c *gin.Context, shortID string) {
+	ttl := h.Config.Password.CookieTTL
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     unlockCookieName,
+		Value:    expires + "." + h.unlockSignature(shortID, expires),
+		Path:     c.Request.URL.Path,
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// validUnlockCookie reports whether the request carries an unexpired unlock cookie for the link
+func (h *ShortlinkHandler) validUnlockCookie(c *gin.Context, shortID string) bool {
+	value, err := c.Cookie(unlockCookieName)
+	if err != nil {
+		return false
+	}
+
+	expires, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(h.unlockSignature(shortID, expires)))
+}
+
+func (h *ShortlinkHandler) unlockSignature(shortID, expires string) string {
+	mac := hmac.New(sha256.New, h.UnlockSecret)
+	mac.Write([]byte(shortID))
+	mac.Write([]byte{'|'})
+	mac.Write([]byte(expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/page"
+)
+
+// previewSuffix appended to a short ID asks for the preview page instead of a redirect
+const previewSuffix = "+"
+
+// previewID strips the preview suffix from shortID and reports whether it was present.
+// The suffix is left alone when previews are disabled.
+func (h *ShortlinkHandler) previewID(shortID string) (string, bool) {
+	if !h.Config.Pages.Preview {
+		return shortID, false
+	}
+	return strings.CutSuffix(shortID, previewSuffix)
+}
+
+// servePreview renders the link's default destination, creation date and click count
+func (h *ShortlinkHandler) servePreview(c *gin.Context, link *model.Link) {
+	var clicks int64
+	if h.Clicks != nil {
+		clicks = h.Clicks.Stats(link.ShortID).Clicks
+	}
+
+	// Continue through the real short URL so the click is counted, keeping any signature
+	continueURL := strings.TrimSuffix(c.Request.URL.Path, previewSuffix)
+	if c.Request.URL.RawQuery != "" {
+		continueURL += "?" + c.Request.URL.RawQuery
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.HTML(http.StatusOK, "preview.html", page.PreviewData{
+		Theme:          h.theme(),
+		ShortURL:       h.shortURL(link.ShortID),
+		DestinationURL: link.OriginalURL,
+		ContinueURL:    continueURL,
+		Targeted:       len(link.Rules) > 0 || len(link.Variants) > 0,
+		CreatedAt:      link.CreatedAt,
+		Clicks:         clicks,
+	})
+}
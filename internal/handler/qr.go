@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hohotang/shortlink-gateway/internal/middleware"
+	"github.com/hohotang/shortlink-gateway/internal/qr"
+	"github.com/hohotang/shortlink-gateway/internal/service"
+	"go.uber.org/zap"
+)
+
+// QRCode handles QR code image requests for a short link
+// @Summary      QR code for a short link
+// @Description  Renders the short URL as a QR code image. Responses carry an ETag and can be revalidated with If-None-Match.
+// @Tags         urls
+// @Produce      png
+// @Produce      image/svg+xml
+// @Param        shortID  path      string  true   "Short URL ID"
+// @Param        format   query     string  false  "Image format"  Enums(png, svg)  default(png)
+// @Param        size     query     int     false  "Width and height in pixels"  default(256)
+// @Param        level    query     string  false  "Error correction level"  Enums(L, M, Q, H)  default(M)
+// @Param        margin   query     int     false  "Quiet zone in modules"  default(4)
+// @Param        fg       query     string  false  "Foreground color as hex"  default(000000)
+// @Param        bg       query     string  false  "Background color as hex"  default(ffffff)
+// @Success      200      {file}    file    "QR code image"
+// @Success      304      {string}  string  "Not Modified"
+// @Failure      400      {object}  map[string]string  "Bad Request, or content too long for a QR code"
+// @Failure      404      {object}  map[string]string  "Short URL not found"
+// @Failure      500      {object}  map[string]string  "Internal Server Error"
+// @Router       /v1/links/{shortID}/qr [get]
+func (h *ShortlinkHandler) QRCode(c *gin.Context) {
+	shortID := c.Param("shortID")
+	if shortID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing short ID"})
+		return
+	}
+
+	opts, err := h.parseQROptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only existing links are rendered, so made-up IDs cannot fill the cache
+	if _, err := h.URLService.ExpandURL(c.Request.Context(), shortID); err != nil {
+		if service.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Short URL not found"})
+			return
+		}
+		logger := middleware.GetLogger(c.Request.Context())
+		logger.Error("Failed to look up link for QR code", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render QR code"})
+		return
+	}
+
+	content := h.shortURL(shortID)
+	etag := opts.ETag(content)
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.Config.QR.MaxAge.Seconds())))
+
+	if match := c.GetHeader("If-None-Match"); match != "" && etagMatches(match, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	img, ok := h.QRCache.Get(etag)
+	h.Metrics.CacheLookup(c.Request.Context(), "qr", ok)
+	if !ok {
+		img, err = qr.Render(content, opts)
+		if errors.Is(err, qr.ErrContentTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Short URL too long for a QR code at this error correction level"})
+			return
+		}
+		if err != nil {
+			logger := middleware.GetLogger(c.Request.Context())
+			logger.Error("Failed to render QR code", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render QR code"})
+			return
+		}
+		h.QRCache.Set(etag, img)
+	}
+
+	c.Data(http.StatusOK, opts.Format.ContentType(), img)
+}
+
+// defaultQROptions returns the configured QR rendering defaults
+func (h *ShortlinkHandler) defaultQROptions() qr.Options {
+	return qr.Options{
+		Format:     qr.FormatPNG,
+		Size:       h.Config.QR.DefaultSize,
+		Level:      h.Config.QR.DefaultLevel,
+		Margin:     h.Config.QR.DefaultMargin,
+		Foreground: qr.Black,
+		Background: qr.White,
+	}
+}
+
+// parseQROptions overrides the defaults with the query parameters of the request
+func (h *ShortlinkHandler) parseQROptions(c *gin.Context) (qr.Options, error) {
+	opts := h.defaultQROptions()
+
+	if format := c.Query("format"); format != "" {
+		opts.Format = qr.Format(strings.ToLower(format))
+		if opts.Format != qr.FormatPNG && opts.Format != qr.FormatSVG {
+			return opts, fmt.Errorf("format must be png or svg")
+		}
+	}
+
+	if size := c.Query("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 32 || n > h.Config.QR.MaxSize {
+			return opts, fmt.Errorf("size must be between 32 and %d", h.Config.QR.MaxSize)
+		}
+		opts.Size = n
+	}
+
+	if level := c.Query("level"); level != "" {
+		switch opts.Level = strings.ToUpper(level); opts.Level {
+		case "L", "M", "Q", "H":
+		default:
+			return opts, fmt.Errorf("level must be one of L, M, Q, H")
+		}
+	}
+
+	if margin := c.Query("margin"); margin != "" {
+		n, err := strconv.Atoi(margin)
+		if err != nil || n < 0 || n > 16 {
+			return opts, fmt.Errorf("margin must be between 0 and 16")
+		}
+		opts.Margin = n
+	}
+
+	var err error
+	if fg := c.Query("fg"); fg != "" {
+		if opts.Foreground, err = qr.ParseColor(fg); err != nil {
+			return opts, err
+		}
+	}
+	if bg := c.Query("bg"); bg != "" {
+		if opts.Background, err = qr.ParseColor(bg); err != nil {
+			return opts, err
+		}
+	}
+
+	return opts, nil
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
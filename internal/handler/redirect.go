@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/redirect"
+	"github.com/hohotang/shortlink-gateway/internal/signing"
+)
+
+// redirectOptions returns the link's redirect options with the configured defaults filled in
+func (h *ShortlinkHandler) redirectOptions(link *model.Link) model.RedirectOptions {
+	opts := link.Redirect
+	if opts.Status == 0 {
+		opts.Status = h.Config.Redirect.Status
+	}
+	if opts.Query == "" {
+		opts.Query = model.QueryMode(h.Config.Redirect.Query)
+	}
+	if opts.Fragment == "" {
+		opts.Fragment = model.FragmentMode(h.Config.Redirect.Fragment)
+	}
+	return opts
+}
+
+// location builds the URL the visitor is sent to, forwarding the short URL's
+// query string when enabled. Signature parameters are never forwarded.
+func (h *ShortlinkHandler) location(c *gin.Context, link *model.Link, destination string) string {
+	opts := h.redirectOptions(link)
+
+	incoming := c.Request.URL.Query()
+	incoming.Del(signing.ParamExpires)
+	incoming.Del(signing.ParamKeyID)
+	incoming.Del(signing.ParamSignature)
+
+	return redirect.Location(destination, incoming, opts.Query, opts.Fragment)
+}
+
+// redirect sends the visitor to location with the link's status code and a
+// Cache-Control header that lets caches keep only redirects that are the same
+// for every visitor
+func (h *ShortlinkHandler) redirect(c *gin.Context, link *model.Link, location string) {
+	status := h.redirectOptions(link).Status
+	if !redirect.ValidStatus(status) {
+		status = http.StatusFound
+	}
+
+	cacheable := len(link.Rules) == 0 && len(link.Variants) == 0 &&
+		!link.PasswordProtected && !link.SignatureRequired && !h.Config.Signing.Required &&
+		!(h.Classifier != nil && h.Config.Classifier.UnfurlPage)
+
+	c.Header("Cache-Control", redirect.CacheControl(status, cacheable, h.Config.Redirect.PermanentMaxAge))
+	c.Redirect(status, location)
+}
+
+// validateRedirect checks per-link redirect options and returns an error message, or "" if they are valid
+func validateRedirect(opts model.RedirectOptions) string {
+	if opts.Status != 0 && !redirect.ValidStatus(opts.Status) {
+		return "Redirect status must be 301, 302, 307 or 308"
+	}
+	switch opts.Query {
+	case "", model.QueryOff, model.QueryKeep, model.QueryOverride, model.QueryAppend:
+	default:
+		return "Invalid redirect query mode"
+	}
+	switch opts.Fragment {
+	case "", model.FragmentDestination, model.FragmentVisitor:
+	default:
+		return "Invalid redirect fragment mode"
+	}
+	return ""
+}
@@ -1,36 +1,82 @@
 package handler
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hohotang/shortlink-gateway/internal/analytics"
+	"github.com/hohotang/shortlink-gateway/internal/blocklist"
+	"github.com/hohotang/shortlink-gateway/internal/cache"
+	"github.com/hohotang/shortlink-gateway/internal/chain"
+	"github.com/hohotang/shortlink-gateway/internal/config"
+	"github.com/hohotang/shortlink-gateway/internal/geoip"
 	"github.com/hohotang/shortlink-gateway/internal/middleware"
 	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/otel"
+	"github.com/hohotang/shortlink-gateway/internal/principal"
+	"github.com/hohotang/shortlink-gateway/internal/qr"
+	"github.com/hohotang/shortlink-gateway/internal/ratelimit"
 	"github.com/hohotang/shortlink-gateway/internal/service"
+	"github.com/hohotang/shortlink-gateway/internal/signing"
+	"github.com/hohotang/shortlink-gateway/internal/useragent"
+	"github.com/hohotang/shortlink-gateway/internal/webhook"
 	"go.uber.org/zap"
 )
 
 type ShortlinkHandler struct {
 	// Dependencies can be injected here
 	URLService service.URLService
+	Config     *config.Config
+
+	// Optional components, a nil value disables the feature
+	Classifier *useragent.Classifier
+	GeoIP      *geoip.Resolver
+	Clicks     *analytics.Recorder
+	QRCache    *cache.LRU[string, []byte]
+	Blocklist  *blocklist.Blocklist
+	Chain      *chain.Resolver
+
+	// Password protected links
+	PasswordGuard *ratelimit.Lockout
+	UnlockSecret  []byte
+
+	// Signer mints and verifies signed short URLs, nil when signing is disabled
+	Signer *signing.Signer
+
+	// Webhooks delivers link events to subscribers, nil when webhooks are disabled
+	Webhooks *webhook.Dispatcher
+
+	// Metrics records business metrics, nil records nothing
+	Metrics *otel.Metrics
 }
 
 // NewShortlinkHandler creates a new ShortlinkHandler with the given URLService
-func NewShortlinkHandler(urlService service.URLService) *ShortlinkHandler {
+func NewShortlinkHandler(cfg *config.Config, urlService service.URLService) *ShortlinkHandler {
 	return &ShortlinkHandler{
 		URLService: urlService,
+		Config:     cfg,
 	}
 }
 
 // Shorten handles URL shortening requests
 // @Summary      Shorten a URL
-// @Description  Creates a short URL from a long URL
+// @Description  Creates a short URL from a long URL.
+// @Description  An optional utm block, or a campaign preset from the gateway config, appends utm_* parameters to every destination; existing utm_* parameters are kept unless override is set.
+// @Description  With reuse_existing the caller's oldest link with the same normalized URL and settings is returned instead of creating a new one, and reused is set in the response. Password protected links are never reused.
 // @Tags         urls
 // @Accept       json
 // @Produce      json
-// @Param        request  body      model.ShortenRequest  true  "URL to shorten"
-// @Success      200      {object}  map[string]string  "Returns shortened URL"
-// @Failure      400      {object}  map[string]string  "Bad Request"
+// @Param        request          body      model.ShortenRequest  true   "URL to shorten"
+// @Param        Idempotency-Key  header    string                false  "Replays the first response to retries with the same key for idempotency.ttl (24h by default)"
+// @Success      200      {object}  map[string]interface{}  "Returns shortened URL, the QR code as a data URI and a signed URL if requested"
+// @Failure      400      {object}  map[string]string  "Bad Request, or a blocked or short URL destination with its reason code"
+// @Failure      409      {object}  map[string]string  "A request with the same idempotency key is still in progress"
+// @Failure      422      {object}  map[string]string  "Idempotency key reused with a different request"
 // @Failure      500      {object}  map[string]string  "Internal Server Error"
 // @Router       /v1/shorten [post]
 func (h *ShortlinkHandler) Shorten(c *gin.Context) {
@@ -41,8 +87,82 @@ func (h *ShortlinkHandler) Shorten(c *gin.Context) {
 		return
 	}
 
+	for i, rule := range req.Rules {
+		if !isHTTPURL(rule.DestinationURL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid destination_url in rule %d", i)})
+			return
+		}
+	}
+
+	if msg := validateVariants(req.Variants); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	switch req.Stickiness {
+	case "", model.StickinessNone, model.StickinessCookie, model.StickinessHash:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stickiness"})
+		return
+	}
+
+	// Passwords are stored as bcrypt hashes, which only cover the first 72 bytes
+	if len(req.Password) > model.MaxPasswordBytes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Password must be at most %d bytes", model.MaxPasswordBytes)})
+		return
+	}
+
+	var redirectOpts model.RedirectOptions
+	if req.Redirect != nil {
+		if msg := validateRedirect(*req.Redirect); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		redirectOpts = *req.Redirect
+	}
+
+	if !h.checkChaining(c, &req) {
+		return
+	}
+
+	if msg := h.applyUTM(&req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if !h.checkDestinations(c, &req) {
+		return
+	}
+
+	var signedTTL time.Duration
+	if req.Signed != nil {
+		if h.Signer == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Signed links are not enabled"})
+			return
+		}
+		ttl, err := h.signedTTL(*req.Signed)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		signedTTL = ttl
+	}
+
+	link := &model.Link{
+		OriginalURL: req.OriginalURL,
+		Rules:       req.Rules,
+		Variants:    req.Variants,
+		Stickiness:  req.Stickiness,
+		Password:    req.Password,
+		Owner:       principal.FromRequest(c.Request, c.ClientIP()),
+
+		SignatureRequired: req.Signed != nil,
+		Interstitial:      req.Interstitial,
+		Redirect:          redirectOpts,
+	}
+
 	// Call the injected URL service with request context
-	shortID, err := h.URLService.ShortenURL(c.Request.Context(), req.OriginalURL)
+	shortID, reused, err := h.createOrReuse(c.Request.Context(), link, req.ReuseExisting)
 	if err != nil {
 		logger := middleware.GetLogger(c.Request.Context())
 		logger.Error("Failed to shorten URL", zap.Error(err))
@@ -50,7 +170,118 @@ func (h *ShortlinkHandler) Shorten(c *gin.Context) {
 		return
 	}
 
-	shortURL := "http://localhost:8080/" + shortID
+	resp := gin.H{"short_url": h.shortURL(shortID)}
+	if reused {
+		resp["reused"] = true
+	}
+
+	if req.QRCode {
+		dataURI, err := qr.DataURI(h.shortURL(shortID), h.defaultQROptions())
+		if err != nil {
+			logger := middleware.GetLogger(c.Request.Context())
+			logger.Error("Failed to render QR code", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render QR code"})
+			return
+		}
+		resp["qr_code"] = dataURI
+	}
+
+	var signedExpires time.Time
+	if req.Signed != nil {
+		signedURL, expires := h.signedURL(shortID, signedTTL)
+		resp["signed_url"] = signedURL
+		resp["expires_at"] = expires.UTC().Format(time.RFC3339)
+		signedExpires = expires
+	}
+
+	if !reused {
+		h.Metrics.LinkCreated(c.Request.Context(), link.OriginalURL, principal.Class(link.Owner))
+		h.publishCreated(c.Request.Context(), link, shortID, signedExpires)
+	} else if !signedExpires.IsZero() {
+		h.publishExpiry(c.Request.Context(), shortID, signedExpires)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// createOrReuse creates link, or with reuse returns the owner's existing link
+// with the same normalized URL and settings if there is one
+func (h *ShortlinkHandler) createOrReuse(ctx context.Context, link *model.Link, reuse bool) (string, bool, error) {
+	if reuse {
+		shortID, err := h.URLService.FindURL(ctx, link.Owner, link)
+		if err != nil {
+			return "", false, err
+		}
+		if shortID != "" {
+			return shortID, true, nil
+		}
+	}
+
+	shortID, err := h.URLService.ShortenURL(ctx, link)
+	return shortID, false, err
+}
+
+// validateVariants checks split variants and returns an error message, or "" if they are valid
+func validateVariants(variants []model.Variant) string {
+	if len(variants) > model.MaxVariants {
+		return fmt.Sprintf("At most %d variants are allowed", model.MaxVariants)
+	}
+	names := make(map[string]bool, len(variants))
+	for i, v := range variants {
+		if !isHTTPURL(v.DestinationURL) {
+			return fmt.Sprintf("Invalid destination_url in variant %d", i)
+		}
+		if v.Name == "" || names[v.Name] {
+			return fmt.Sprintf("Variant %d needs a unique name", i)
+		}
+		if !validVariantName(v.Name) {
+			return fmt.Sprintf("Variant %d name must be at most %d letters, digits, '-', '_' or '.'", i, model.MaxVariantNameLength)
+		}
+		if v.Weight == 0 {
+			return fmt.Sprintf("Variant %q needs a positive weight", v.Name)
+		}
+		names[v.Name] = true
+	}
+	return ""
+}
+
+// validVariantName reports whether name is short and only uses letters, digits, '-', '_' and '.'
+func validVariantName(name string) bool {
+	if len(name) > model.MaxVariantNameLength {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// destinationFields returns the JSON field names and pointers to all destination URLs of a request
+func destinationFields(req *model.ShortenRequest) ([]string, []*string) {
+	fields := []string{"original_url"}
+	destinations := []*string{&req.OriginalURL}
+	for i := range req.Rules {
+		fields = append(fields, fmt.Sprintf("rules[%d].destination_url", i))
+		destinations = append(destinations, &req.Rules[i].DestinationURL)
+	}
+	for i := range req.Variants {
+		fields = append(fields, fmt.Sprintf("variants[%d].destination_url", i))
+		destinations = append(destinations, &req.Variants[i].DestinationURL)
+	}
+	return fields, destinations
+}
+
+// isHTTPURL reports whether raw is an absolute http or https URL
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
 
-	c.JSON(http.StatusOK, gin.H{"short_url": shortURL})
+// shortURL builds the public URL of a short link
+func (h *ShortlinkHandler) shortURL(shortID string) string {
+	return strings.TrimRight(h.Config.BaseURL, "/") + "/" + shortID
 }
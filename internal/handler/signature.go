@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hohotang/shortlink-gateway/internal/middleware"
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/service"
+	"github.com/hohotang/shortlink-gateway/internal/signing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Sign handles requests to mint a signed, expiring URL for an existing short link
+// @Summary      Sign a short URL
+// @Description  Mints a short URL that is only valid until it expires. The signature is carried in the exp, kid and sig query parameters:
+// @Description  exp is the expiry as a Unix timestamp, kid names the signing key and
+// @Description  sig = base64url(HMAC-SHA256(key[kid], "v1\n" + kid + "\n" + shortID + "\n" + exp)) without padding.
+// @Description  Signatures made with any configured key are accepted, so keys can be rotated by adding a new key ID before retiring the old one.
+// @Description  Webhook subscribers receive link.updated now and link.expired when the signed URL expires.
+// @Tags         urls
+// @Accept       json
+// @Produce      json
+// @Security     AdminToken
+// @Param        shortID  path      string               true   "Short URL ID"
+// @Param        request  body      model.SignedOptions  false  "Lifetime of the signed URL"
+// @Success      200      {object}  map[string]string  "Returns the signed URL, its expiry and the key ID"
+// @Failure      400      {object}  map[string]string  "Bad Request"
+// @Failure      401      {object}  map[string]string  "Unauthorized"
+// @Failure      403      {object}  map[string]string  "Admin API is disabled"
+// @Failure      404      {object}  map[string]string  "Short URL not found"
+// @Failure      500      {object}  map[string]string  "Internal Server Error"
+// @Router       /v1/links/{shortID}/sign [post]
+func (h *ShortlinkHandler) Sign(c *gin.Context) {
+	shortID := c.Param("shortID")
+	if shortID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing short ID"})
+		return
+	}
+
+	if h.Signer == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Signed links are not enabled"})
+		return
+	}
+
+	var opts model.SignedOptions
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+
+	ttl, err := h.signedTTL(opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only existing links are signed, so made-up IDs cannot schedule expiry events
+	link, err := h.URLService.ExpandURL(c.Request.Context(), shortID)
+	if err != nil {
+		if service.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Short URL not found"})
+			return
+		}
+		logger := middleware.GetLogger(c.Request.Context())
+		logger.Error("Failed to look up link for signing", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign URL"})
+		return
+	}
+
+	signedURL, expires := h.signedURL(shortID, ttl)
+	h.publishSigned(c.Request.Context(), link, shortID, expires)
+	c.JSON(http.StatusOK, gin.H{
+		"signed_url": signedURL,
+		"expires_at": expires.UTC().Format(time.RFC3339),
+		"key_id":     h.Signer.ActiveKeyID(),
+	})
+}
+
+// checkSignature verifies the signature query parameters before the core is called.
+// It reports whether the request was validly signed, and whether it may proceed;
+// when it may not, the response has already been written.
+func (h *ShortlinkHandler) checkSignature(c *gin.Context, shortID string) (signed, ok bool) {
+	if h.Signer == nil {
+		return false, true
+	}
+
+	err := h.Signer.Verify(shortID, c.Request.URL.Query(), time.Now())
+	span := trace.SpanFromContext(c.Request.Context())
+
+	switch {
+	case err == nil:
+		span.SetAttributes(attribute.String("link.signature", "valid"))
+		return true, true
+	case errors.Is(err, signing.ErrMissing):
+		if !h.Config.Signing.Required {
+			return false, true
+		}
+		span.SetAttributes(attribute.String("link.signature", "missing"))
+		c.JSON(http.StatusForbidden, gin.H{"error": "Signed URL required"})
+	case errors.Is(err, signing.ErrExpired):
+		span.SetAttributes(attribute.String("link.signature", "expired"))
+		c.JSON(http.StatusGone, gin.H{"error": "Signed URL expired"})
+	default:
+		span.SetAttributes(attribute.String("link.signature", "invalid"))
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid signature"})
+	}
+	return false, false
+}
+
+// signedTTL resolves the requested lifetime of a signed URL against the configured limits
+func (h *ShortlinkHandler) signedTTL(opts model.SignedOptions) (time.Duration, error) {
+	if opts.ExpiresIn == "" {
+		return h.Config.Signing.DefaultTTL, nil
+	}
+
+	ttl, err := time.ParseDuration(opts.ExpiresIn)
+	if err != nil || ttl <= 0 {
+		return 0, errors.New("expires_in must be a positive duration such as 24h")
+	}
+	if ttl > h.Config.Signing.MaxTTL {
+		return 0, fmt.Errorf("expires_in must not exceed %s", h.Config.Signing.MaxTTL)
+	}
+	return ttl, nil
+}
+
+// signedURL builds the public URL of a short link signed to expire after ttl
+func (h *ShortlinkHandler) signedURL(shortID string, ttl time.Duration) (string, time.Time) {
+	expires := time.Now().Add(ttl)
+	return h.shortURL(shortID) + "?" + h.Signer.Sign(shortID, expires).Encode(), expires
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hohotang/shortlink-gateway/internal/model"
+)
+
+// visitorKey returns the identity used to assign this visitor to a split variant.
+// Sticky links derive it from a long-lived cookie or from the client IP and user
+// agent; otherwise a fresh random key gives an independent draw on every visit.
+// The short ID is mixed in so assignments on different links are independent.
+// newCookie is set when the visitor has no split cookie yet; it is only issued
+// by setVisitorCookie once a variant is actually assigned.
+func (h *ShortlinkHandler) visitorKey(c *gin.Context, link *model.Link) (key, newCookie string) {
+	if len(link.Variants) == 0 {
+		return "", ""
+	}
+
+	switch link.Stickiness {
+	case model.StickinessCookie:
+		id, err := c.Cookie(h.Config.Split.CookieName)
+		if err != nil || id == "" {
+			id = randomID()
+			newCookie = id
+		}
+		return link.ShortID + ":" + id, newCookie
+	case model.StickinessHash:
+		return link.ShortID + ":" + c.ClientIP() + "\x00" + c.Request.UserAgent(), ""
+	default:
+		return randomID(), ""
+	}
+}
+
+// setVisitorCookie issues the split cookie holding the visitor ID. The cookie holds a
+// visitor ID rather than the chosen variant, so that a weight change is resolved by
+// rendezvous hashing instead of pinning visitors to stale variants.
+func (h *ShortlinkHandler) setVisitorCookie(c *gin.Context, id string) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     h.Config.Split.CookieName,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(h.Config.Split.CookieMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// randomID returns a random 128-bit hex identifier
+func randomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/urlutil"
+)
+
+// maxUTMValueLength bounds each utm_* value so destinations stay a reasonable length
+const maxUTMValueLength = 256
+
+// applyUTM adds the request's UTM parameters, merged with its campaign preset,
+// to every destination URL of the request. It returns an error message, or ""
+// on success.
+func (h *ShortlinkHandler) applyUTM(req *model.ShortenRequest) string {
+	if req.UTM == nil {
+		return ""
+	}
+	utm := *req.UTM
+
+	if utm.Preset != "" {
+		preset, ok := h.Config.UTM.Presets[strings.ToLower(utm.Preset)]
+		if !ok {
+			return fmt.Sprintf("Unknown UTM preset %q", utm.Preset)
+		}
+		utm.Source = firstNonEmpty(utm.Source, preset.Source)
+		utm.Medium = firstNonEmpty(utm.Medium, preset.Medium)
+		utm.Campaign = firstNonEmpty(utm.Campaign, preset.Campaign)
+		utm.Term = firstNonEmpty(utm.Term, preset.Term)
+		utm.Content = firstNonEmpty(utm.Content, preset.Content)
+	}
+
+	params := []urlutil.Param{
+		{Key: "utm_source", Value: utm.Source},
+		{Key: "utm_medium", Value: utm.Medium},
+		{Key: "utm_campaign", Value: utm.Campaign},
+		{Key: "utm_term", Value: utm.Term},
+		{Key: "utm_content", Value: utm.Content},
+	}
+	for _, p := range params {
+		if len(p.Value) > maxUTMValueLength {
+			return fmt.Sprintf("%s must not exceed %d characters", p.Key, maxUTMValueLength)
+		}
+	}
+
+	fields, destinations := destinationFields(req)
+	for i, destination := range destinations {
+		tagged, err := urlutil.AddParams(*destination, params, utm.Override)
+		if err != nil {
+			return fmt.Sprintf("Invalid %s", fields[i])
+		}
+		*destination = tagged
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hohotang/shortlink-gateway/internal/middleware"
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/webhook"
+	"go.uber.org/zap"
+)
+
+// maxDeliveryLogLimit caps the number of deliveries returned at once
+const maxDeliveryLogLimit = 500
+
+// CreateWebhook handles requests to subscribe an endpoint to link events
+// @Summary      Subscribe to link events
+// @Description  Registers an endpoint that receives link events as signed JSON POST requests. Every request carries the
+// @Description  X-Shortlink-Event, X-Shortlink-Delivery, X-Shortlink-Timestamp and X-Shortlink-Signature headers, where
+// @Description  signature = "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)). Failed deliveries are retried with
+// @Description  exponential backoff and written to the dead letter file once the attempts are exhausted.
+// @Description  The secret is only returned in this response.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Security     AdminToken
+// @Param        request  body      model.WebhookSubscriptionRequest  true  "Endpoint and events"
+// @Success      201      {object}  webhook.Subscription
+// @Failure      400      {object}  map[string]string  "Bad Request"
+// @Failure      401      {object}  map[string]string  "Unauthorized"
+// @Failure      403      {object}  map[string]string  "Admin API is disabled"
+// @Failure      500      {object}  map[string]string  "Internal Server Error"
+// @Router       /v1/webhooks [post]
+func (h *ShortlinkHandler) CreateWebhook(c *gin.Context) {
+	if !h.webhooksEnabled(c) {
+		return
+	}
+
+	var req model.WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.URL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	sub := webhook.Subscription{
+		URL:             req.URL,
+		ClickThresholds: req.ClickThresholds,
+		Secret:          req.Secret,
+		Description:     req.Description,
+	}
+	for _, event := range req.Events {
+		sub.Events = append(sub.Events, webhook.EventType(event))
+	}
+
+	sub, err := h.Webhooks.Subscribe(sub)
+	if errors.Is(err, webhook.ErrInvalidSubscription) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger := middleware.GetLogger(c.Request.Context())
+		logger.Error("Failed to store webhook subscription", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store subscription"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+// ListWebhooks handles requests to list webhook subscriptions
+// @Summary      List webhook subscriptions
+// @Tags         webhooks
+// @Produce      json
+// @Security     AdminToken
+// @Success      200  {array}   webhook.Subscription
+// @Failure      401  {object}  map[string]string  "Unauthorized"
+// @Failure      403  {object}  map[string]string  "Admin API is disabled"
+// @Router       /v1/webhooks [get]
+func (h *ShortlinkHandler) ListWebhooks(c *gin.Context) {
+	if !h.webhooksEnabled(c) {
+		return
+	}
+	c.JSON(http.StatusOK, h.Webhooks.Subscriptions())
+}
+
+// GetWebhook handles requests for a single webhook subscription
+// @Summary      Get a webhook subscription
+// @Tags         webhooks
+// @Produce      json
+// @Security     AdminToken
+// @Param        id   path      string  true  "Subscription ID"
+// @Success      200  {object}  webhook.Subscription
+// @Failure      401  {object}  map[string]string  "Unauthorized"
+// @Failure      403  {object}  map[string]string  "Admin API is disabled"
+// @Failure      404  {object}  map[string]string  "Not Found"
+// @Router       /v1/webhooks/{id} [get]
+func (h *ShortlinkHandler) GetWebhook(c *gin.Context) {
+	if !h.webhooksEnabled(c) {
+		return
+	}
+
+	sub, ok := h.Webhooks.Subscription(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// DeleteWebhook handles requests to delete a webhook subscription
+// @Summary      Delete a webhook subscription
+// @Description  Stops deliveries to the endpoint. Pending deliveries are dead-lettered.
+// @Tags         webhooks
+// @Security     AdminToken
+// @Param        id   path  string  true  "Subscription ID"
+// @Success      204  "No Content"
+// @Failure      401  {object}  map[string]string  "Unauthorized"
+// @Failure      403  {object}  map[string]string  "Admin API is disabled"
+// @Failure      404  {object}  map[string]string  "Not Found"
+// @Failure      500  {object}  map[string]string  "Internal Server Error"
+// @Router       /v1/webhooks/{id} [delete]
+func (h *ShortlinkHandler) DeleteWebhook(c *gin.Context) {
+	if !h.webhooksEnabled(c) {
+		return
+	}
+
+	ok, err := h.Webhooks.Unsubscribe(c.Param("id"))
+	if err != nil {
+		logger := middleware.GetLogger(c.Request.Context())
+		logger.Error("Failed to delete webhook subscription", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete subscription"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// PingWebhook handles requests to send a test event to a subscription
+// @Summary      Send a test event
+// @Description  Queues a webhook.ping event for the subscription, regardless of the events it subscribed to.
+// @Tags         webhooks
+// @Produce      json
+// @Security     AdminToken
+// @Param        id   path      string  true  "Subscription ID"
+// @Success      202  {object}  webhook.Delivery
+// @Failure      401  {object}  map[string]string  "Unauthorized"
+// @Failure      403  {object}  map[string]string  "Admin API is disabled"
+// @Failure      404  {object}  map[string]string  "Not Found"
+// @Failure      503  {object}  map[string]string  "Gateway is shutting down"
+// @Router       /v1/webhooks/{id}/ping [post]
+func (h *ShortlinkHandler) PingWebhook(c *gin.Context) {
+	if !h.webhooksEnabled(c) {
+		return
+	}
+
+	delivery, err := h.Webhooks.Ping(c.Param("id"))
+	h.respondDelivery(c, delivery, err)
+}
+
+// WebhookDeliveries handles requests for the delivery log of a subscription
+// @Summary      Webhook delivery log
+// @Description  Returns the most recent deliveries of a subscription, newest first, with their attempts, last response and payload.
+// @Description  Dead-lettered deliveries are also kept in the dead letter file after they leave the log.
+// @Tags         webhooks
+// @Produce      json
+// @Security     AdminToken
+// @Param        id      path      string  true   "Subscription ID"
+// @Param        status  query     string  false  "Only deliveries in this state"  Enums(pending, succeeded, dead)
+// @Param        limit   query     int     false  "Maximum number of deliveries"  default(50)
+// @Success      200     {array}   webhook.Delivery
+// @Failure      400     {object}  map[string]string  "Bad Request"
+// @Failure      401     {object}  map[string]string  "Unauthorized"
+// @Failure      403     {object}  map[string]string  "Admin API is disabled"
+// @Failure      404     {object}  map[string]string  "Not Found"
+// @Router       /v1/webhooks/{id}/deliveries [get]
+func (h *ShortlinkHandler) WebhookDeliveries(c *gin.Context) {
+	if !h.webhooksEnabled(c) {
+		return
+	}
+
+	id := c.Param("id")
+	if _, ok := h.Webhooks.Subscription(id); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+
+	status := webhook.Status(c.Query("status"))
+	switch status {
+	case "", webhook.StatusPending, webhook.StatusSucceeded, webhook.StatusDead:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxDeliveryLogLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxDeliveryLogLimit)})
+			return
+		}
+		limit = n
+	}
+
+	c.JSON(http.StatusOK, h.Webhooks.Deliveries(id, status, limit))
+}
+
+// ReplayWebhookDelivery handles requests to send a delivery again
+// @Summary      Replay a webhook delivery
+// @Description  Sends the event of a succeeded or dead-lettered delivery again as a new delivery with the same event ID,
+// @Description  so receivers can deduplicate it. Deliveries are looked up in the delivery log and then in the dead letter file.
+// @Tags         webhooks
+// @Produce      json
+// @Security     AdminToken
+// @Param        id          path      string  true  "Subscription ID"
+// @Param        deliveryID  path      string  true  "Delivery ID"
+// @Success      202         {object}  webhook.Delivery
+// @Failure      401         {object}  map[string]string  "Unauthorized"
+// @Failure      403         {object}  map[string]string  "Admin API is disabled"
+// @Failure      404         {object}  map[string]string  "Not Found"
+// @Failure      409         {object}  map[string]string  "Delivery is still pending"
+// @Failure      503         {object}  map[string]string  "Gateway is shutting down"
+// @Router       /v1/webhooks/{id}/deliveries/{deliveryID}/replay [post]
+func (h *ShortlinkHandler) ReplayWebhookDelivery(c *gin.Context) {
+	if !h.webhooksEnabled(c) {
+		return
+	}
+
+	delivery, err := h.Webhooks.Replay(c.Param("id"), c.Param("deliveryID"))
+	h.respondDelivery(c, delivery, err)
+}
+
+// respondDelivery answers with a newly queued delivery or the reason it was not queued
+func (h *ShortlinkHandler) respondDelivery(c *gin.Context, delivery webhook.Delivery, err error) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusAccepted, delivery)
+	case errors.Is(err, webhook.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery or subscription not found"})
+	case errors.Is(err, webhook.ErrPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "Delivery is still pending"})
+	case errors.Is(err, webhook.ErrClosed):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gateway is shutting down"})
+	default:
+		logger := middleware.GetLogger(c.Request.Context())
+		logger.Error("Failed to queue webhook delivery", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue delivery"})
+	}
+}
+
+// webhooksEnabled answers the request when webhooks are disabled
+func (h *ShortlinkHandler) webhooksEnabled(c *gin.Context) bool {
+	if h.Webhooks == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Webhooks are not enabled"})
+		return false
+	}
+	return true
+}
+
+// publishCreated notifies subscribers of a new link and schedules the expiry
+// event of its signed URL
+func (h *ShortlinkHandler) publishCreated(ctx context.Context, link *model.Link, shortID string, signedExpires time.Time) {
+	if h.Webhooks == nil {
+		return
+	}
+
+	h.Webhooks.Publish(webhook.EventLinkCreated, webhook.LinkData{
+		ShortID:     shortID,
+		ShortURL:    h.shortURL(shortID),
+		OriginalURL: link.OriginalURL,
+		Owner:       link.Owner,
+	})
+	if !signedExpires.IsZero() {
+		h.publishExpiry(ctx, shortID, signedExpires)
+	}
+}
+
+// publishSigned notifies subscribers that a signed URL was minted for the link
+// and schedules its expiry event
+func (h *ShortlinkHandler) publishSigned(ctx context.Context, link *model.Link, shortID string, expires time.Time) {
+	if h.Webhooks == nil {
+		return
+	}
+
+	signedExpires := expires.UTC()
+	h.Webhooks.Publish(webhook.EventLinkUpdated, webhook.LinkData{
+		ShortID:     shortID,
+		ShortURL:    h.shortURL(shortID),
+		OriginalURL: link.OriginalURL,
+		Owner:       link.Owner,
+		ExpiresAt:   &signedExpires,
+		KeyID:       h.Signer.ActiveKeyID(),
+	})
+	h.publishExpiry(ctx, shortID, expires)
+}
+
+// publishExpiry schedules link.expired for when a signed URL of the link expires
+func (h *ShortlinkHandler) publishExpiry(ctx context.Context, shortID string, expires time.Time) {
+	if h.Webhooks == nil {
+		return
+	}
+
+	expires = expires.UTC()
+	err := h.Webhooks.PublishAt(expires, webhook.EventLinkExpired, webhook.LinkData{
+		ShortID:   shortID,
+		ShortURL:  h.shortURL(shortID),
+		ExpiresAt: &expires,
+		KeyID:     h.Signer.ActiveKeyID(),
+	})
+	if err != nil {
+		middleware.GetLogger(ctx).Warn("link.expired webhook not scheduled", zap.String("short_id", shortID), zap.Error(err))
+	}
+}
+
+// publishClicks notifies subscribers whose click threshold the link just reached.
+// clicks counts human clicks recorded by this gateway process only.
+func (h *ShortlinkHandler) publishClicks(shortID string, clicks int64) {
+	if h.Webhooks == nil {
+		return
+	}
+
+	h.Webhooks.Clicks(webhook.LinkData{
+		ShortID:  shortID,
+		ShortURL: h.shortURL(shortID),
+		Clicks:   clicks,
+	})
+}
@@ -0,0 +1,144 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// sweepEvery controls how often expired keys are purged, counted in calls to Do
+const sweepEvery = 256
+
+var (
+	// ErrMismatch means a key was reused with a different request
+	ErrMismatch = errors.New("idempotency key reused with a different request")
+	// ErrFull means the store holds its maximum number of keys
+	ErrFull = errors.New("idempotency store is full")
+)
+
+// Response is a stored response, replayed for retries with the same key
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+	Discard     bool // the response is not stored, e.g. because it was too large to keep
+}
+
+// Store remembers the first response per key for a while and replays it to
+// retries. Concurrent requests with the same key are coalesced: only the first
+// one runs, the others wait for its response.
+type Store struct {
+	ttl     time.Duration
+	maxKeys int
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	calls   int
+}
+
+type entry struct {
+	fingerprint string
+	done        chan struct{} // closed once the first request has finished
+	resp        *Response     // nil if the first request did not complete
+	expires     time.Time
+}
+
+// NewStore creates a store keeping responses for ttl and at most maxKeys keys
+func NewStore(ttl time.Duration, maxKeys int) *Store {
+	return &Store{
+		ttl:     ttl,
+		maxKeys: maxKeys,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Do runs fn for the first request with key and stores its response. Later and
+// concurrent requests with the same key and fingerprint get that response with
+// replayed set, and requests with a different fingerprint get ErrMismatch.
+// Server errors are returned to the requests waiting on them but not stored, so
+// a later retry runs again. Discarded responses are neither stored nor shared,
+// the waiting requests run again instead.
+func (s *Store) Do(ctx context.Context, key, fingerprint string, fn func() Response) (resp Response, replayed bool, err error) {
+	for {
+		e, owner, err := s.begin(key, fingerprint)
+		if err != nil {
+			return Response{}, false, err
+		}
+		if owner {
+			return s.run(key, e, fn), false, nil
+		}
+
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return Response{}, false, ctx.Err()
+		}
+		if e.resp != nil {
+			return *e.resp, true, nil
+		}
+		// The first request panicked or was aborted, try to take over
+	}
+}
+
+// begin returns the entry for key, creating it when the caller is the first
+func (s *Store) begin(key, fingerprint string) (*entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.calls++
+	if s.calls%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	if e, ok := s.entries[key]; ok && (e.expires.IsZero() || now.Before(e.expires)) {
+		if e.fingerprint != fingerprint {
+			return nil, false, ErrMismatch
+		}
+		return e, false, nil
+	}
+
+	if len(s.entries) >= s.maxKeys {
+		s.sweep(now)
+		if len(s.entries) >= s.maxKeys {
+			return nil, false, ErrFull
+		}
+	}
+
+	e := &entry{fingerprint: fingerprint, done: make(chan struct{})}
+	s.entries[key] = e
+	return e, true, nil
+}
+
+// run executes fn for the owner of an entry and publishes its response
+func (s *Store) run(key string, e *entry, fn func() Response) (resp Response) {
+	completed := false
+	defer func() {
+		s.mu.Lock()
+		if completed && resp.Status < 500 && !resp.Discard {
+			e.resp = &resp
+			e.expires = time.Now().Add(s.ttl)
+		} else {
+			if completed && !resp.Discard {
+				e.resp = &resp
+			}
+			delete(s.entries, key)
+		}
+		s.mu.Unlock()
+		close(e.done)
+	}()
+
+	resp = fn()
+	completed = true
+	return resp
+}
+
+// sweep removes finished entries past their expiry, the caller must hold s.mu
+func (s *Store) sweep(now time.Time) {
+	for key, e := range s.entries {
+		if !e.expires.IsZero() && !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type contextKey struct{}
+
+// WithContext returns a copy of ctx carrying l as the request-scoped logger
+func WithContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the request-scoped logger of ctx, or the global logger
+// outside of a request
+func FromContext(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*zap.Logger); ok {
+		return l
+	}
+	return zap.L()
+}
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DebugToken returns a token that turns on debug logging for requests that
+// carry it until it expires: "<exp>.<hex(HMAC-SHA256(secret, "debug." + exp))>"
+// with exp as a Unix timestamp
+func DebugToken(secret []byte, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + debugSignature(secret, exp)
+}
+
+// VerifyDebugToken reports whether token was minted with secret and has not expired
+func VerifyDebugToken(secret []byte, token string, now time.Time) bool {
+	if len(secret) == 0 {
+		return false
+	}
+
+	exp, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(debugSignature(secret, exp)))
+}
+
+func debugSignature(secret []byte, exp string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("debug." + exp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -1,13 +1,24 @@
 package logger
 
 import (
+	"context"
+	"fmt"
 	"strings"
 
+	"go.opentelemetry.io/contrib/bridges/otelzap"
+	otellog "go.opentelemetry.io/otel/log"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var log *zap.Logger
+var (
+	log   *zap.Logger
+	debug *zap.Logger
+	base  *zap.Logger // writes every level to stdout
+
+	// level gates the global logger and can be changed at runtime
+	level = zap.NewAtomicLevel()
+)
 
 // Init initializes the global logger
 func Init(serviceName string, env string) {
@@ -15,8 +26,8 @@ func Init(serviceName string, env string) {
 	config.Encoding = "json"
 
 	// 改變 log level 根據環境
+	level.SetLevel(defaultLevel(env))
 	if strings.ToLower(env) == "dev" {
-		config.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
 		config.EncoderConfig.TimeKey = "time"
 		config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 		config.EncoderConfig.CallerKey = "caller"
@@ -26,7 +37,6 @@ func Init(serviceName string, env string) {
 		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 		config.OutputPaths = []string{"stdout"}
 	} else {
-		config.Level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
 		config.EncoderConfig.TimeKey = "timestamp"
 		config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 		config.EncoderConfig.MessageKey = "message"
@@ -36,16 +46,94 @@ func Init(serviceName string, env string) {
 		config.OutputPaths = []string{"stdout"}
 	}
 
-	var err error
-	log, err = config.Build()
+	// The core writes every level, the global logger is filtered by the
+	// adjustable level and the debug logger is not filtered at all
+	config.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	built, err := config.Build()
 	if err != nil {
 		panic(err)
 	}
 
-	log = log.With(zap.String("service", serviceName))
+	base = built.With(zap.String("service", serviceName))
+	setLoggers(base)
+}
+
+// Export tees every log line to an OpenTelemetry log provider in addition to
+// stdout. Loggers created with a Context field, such as the request-scoped
+// logger, emit records correlated with the trace of their context. Loggers
+// returned by L before the call keep writing to stdout only.
+func Export(provider otellog.LoggerProvider, name string) {
+	if base == nil {
+		return
+	}
+
+	exported := otelzap.NewCore(name, otelzap.WithLoggerProvider(provider))
+	setLoggers(base.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, exported)
+	})))
+}
+
+func setLoggers(l *zap.Logger) {
+	log = l.WithOptions(zap.IncreaseLevel(level))
+	debug = l.With(zap.Bool("debug_request", true))
 	zap.ReplaceGlobals(log)
 }
 
+// Context returns a field that carries ctx to the OpenTelemetry log bridge,
+// which takes the trace and span of exported records from it. The field is
+// not written to stdout.
+func Context(ctx context.Context) zap.Field {
+	return zap.Field{Key: "context", Type: zapcore.SkipType, Interface: ctx}
+}
+
+// Level returns the level of the global logger. It implements http.Handler
+// and can be changed at runtime.
+func Level() *zap.AtomicLevel {
+	return &level
+}
+
+// SetLevel changes the level of the global logger to one of debug, info,
+// warn or error. An empty text restores the default of the environment.
+func SetLevel(env string, text string) error {
+	if text == "" {
+		level.SetLevel(defaultLevel(env))
+		return nil
+	}
+
+	l, err := ParseLevel(text)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(l)
+	return nil
+}
+
+// ParseLevel parses one of debug, info, warn or error. The dpanic, panic and
+// fatal levels are rejected, as they would silence errors.
+func ParseLevel(text string) (zapcore.Level, error) {
+	l, err := zapcore.ParseLevel(text)
+	if err != nil || l < zapcore.DebugLevel || l > zapcore.ErrorLevel {
+		return l, fmt.Errorf("unknown log level %q", text)
+	}
+	return l, nil
+}
+
+func defaultLevel(env string) zapcore.Level {
+	if strings.ToLower(env) == "dev" {
+		return zapcore.DebugLevel
+	}
+	return zapcore.InfoLevel
+}
+
+// Debug returns a logger that writes debug messages whatever the global level,
+// for requests that asked for debug logging
+func Debug() *zap.Logger {
+	if debug == nil {
+		return L()
+	}
+	return debug
+}
+
 // L returns the global zap logger instance
 func L() *zap.Logger {
 	if log == nil {
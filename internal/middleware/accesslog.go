@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hohotang/shortlink-gateway/internal/accesslog"
+	"github.com/hohotang/shortlink-gateway/internal/redact"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// AccessLog writes one access log line per request. Unlike the request log it
+// is never sampled, and masked query parameters are redacted from the URI.
+func (m *middleware) AccessLog(l *accesslog.Logger) gin.HandlerFunc {
+	redactor := redact.New(redact.Config{
+		QueryParams: m.config.HTTPLogging.QueryParams,
+		Mask:        m.config.HTTPLogging.Mask,
+	})
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		uri := c.Request.URL.EscapedPath()
+		if query := redactor.Query(c.Request.URL.RawQuery); query != "" {
+			uri += "?" + query
+		}
+
+		c.Next()
+
+		entry := accesslog.Entry{
+			Time:      start,
+			RemoteIP:  c.ClientIP(),
+			Method:    c.Request.Method,
+			URI:       uri,
+			Proto:     c.Request.Proto,
+			Status:    c.Writer.Status(),
+			Bytes:     max(c.Writer.Size(), 0),
+			Duration:  time.Since(start),
+			Referer:   c.Request.Referer(),
+			UserAgent: c.Request.UserAgent(),
+			RequestID: c.Writer.Header().Get(RequestIDHeader),
+		}
+		if user, _, ok := c.Request.BasicAuth(); ok {
+			entry.User = user
+		}
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.HasTraceID() {
+			entry.TraceID = sc.TraceID().String()
+		}
+
+		if err := l.Log(entry); err != nil {
+			m.logger.Warn("Failed to write access log", zap.Error(err))
+		}
+	}
+}
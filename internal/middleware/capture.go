@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPooledBuffer is the largest buffer returned to the pool, so that a single
+// large capture limit does not keep memory alive
+const maxPooledBuffer = 64 * 1024
+
+var bufferPool = sync.Pool{
+	New: func() any { return &bytes.Buffer{} },
+}
+
+// bodyCapture keeps the first limit bytes written to it and counts the rest.
+// A limit of zero or less keeps everything.
+type bodyCapture struct {
+	buf   *bytes.Buffer
+	limit int
+	total int64
+}
+
+func newBodyCapture(limit int) *bodyCapture {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if limit <= 0 {
+		limit = math.MaxInt
+	}
+	return &bodyCapture{buf: buf, limit: limit}
+}
+
+// Write never fails, so that capturing cannot break the stream it observes.
+// Nothing is captured once the capture has been released.
+func (b *bodyCapture) Write(p []byte) (int, error) {
+	if b.buf == nil {
+		return len(p), nil
+	}
+	b.total += int64(len(p))
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+func (b *bodyCapture) WriteString(s string) (int, error) {
+	if b.buf == nil {
+		return len(s), nil
+	}
+	b.total += int64(len(s))
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.WriteString(s[:min(room, len(s))])
+	}
+	return len(s), nil
+}
+
+// Bytes returns the captured prefix. It is only valid until release.
+func (b *bodyCapture) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// Truncated reports whether more bytes passed than were captured
+func (b *bodyCapture) Truncated() bool {
+	return b.total > int64(b.buf.Len())
+}
+
+// Marker describes the part of the body that was not captured
+func (b *bodyCapture) Marker() string {
+	if !b.Truncated() {
+		return ""
+	}
+	return "...[truncated, " + strconv.FormatInt(b.total, 10) + " bytes total]"
+}
+
+// release returns the buffer to the pool
+func (b *bodyCapture) release() {
+	if b.buf == nil {
+		return
+	}
+	if b.buf.Cap() <= maxPooledBuffer {
+		bufferPool.Put(b.buf)
+	}
+	b.buf = nil
+}
+
+// teeReadCloser passes a request body through to the handler and captures the
+// part of it the handler reads
+type teeReadCloser struct {
+	io.ReadCloser
+	capture *bodyCapture
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		t.capture.Write(p[:n])
+	}
+	return n, err
+}
+
+// teeResponseWriter passes the response through and captures its first bytes.
+// Embedding gin.ResponseWriter keeps Flush, Hijack, CloseNotify and Pusher
+// working, so streamed and upgraded responses are unaffected.
+type teeResponseWriter struct {
+	gin.ResponseWriter
+	capture *bodyCapture
+}
+
+func (w *teeResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.capture.Write(b[:n])
+	return n, err
+}
+
+func (w *teeResponseWriter) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	w.capture.WriteString(s[:n])
+	return n, err
+}
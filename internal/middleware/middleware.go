@@ -3,45 +3,36 @@ package middleware
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"runtime/debug"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/hohotang/shortlink-gateway/internal/accesslog"
 	"github.com/hohotang/shortlink-gateway/internal/config"
+	"github.com/hohotang/shortlink-gateway/internal/idempotency"
+	"github.com/hohotang/shortlink-gateway/internal/logger"
 	"github.com/hohotang/shortlink-gateway/internal/otel"
+	"github.com/hohotang/shortlink-gateway/internal/principal"
+	"github.com/hohotang/shortlink-gateway/internal/redact"
 
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-type contextKey struct{}
-
-var loggerKey = contextKey{}
-
-// responseBodyWriter is a struct used to capture response content
-type responseBodyWriter struct {
-	gin.ResponseWriter
-	body *bytes.Buffer
-}
-
-// Write overrides the original Write method to simultaneously write to the response and capture content
-func (r *responseBodyWriter) Write(b []byte) (int, error) {
-	r.body.Write(b)
-	return r.ResponseWriter.Write(b)
-}
-
-// WriteString overrides the original WriteString method
-func (r *responseBodyWriter) WriteString(s string) (int, error) {
-	r.body.WriteString(s)
-	return r.ResponseWriter.WriteString(s)
-}
-
 type middleware struct {
 	config    *config.Config
 	logger    *zap.Logger
@@ -53,6 +44,9 @@ type Middleware interface {
 	LoggingMiddleware() gin.HandlerFunc
 	MetricsMiddleware() gin.HandlerFunc
 	RecoveryMiddleware() gin.HandlerFunc
+	AdminAuth() gin.HandlerFunc
+	Idempotency() gin.HandlerFunc
+	AccessLog(l *accesslog.Logger) gin.HandlerFunc
 }
 
 func NewMiddleware(
@@ -71,16 +65,14 @@ func (m *middleware) Otel() gin.HandlerFunc {
 	return otelgin.Middleware(m.config.ServiceName)
 }
 
-func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
-	return context.WithValue(ctx, loggerKey, logger)
+// WithLogger stores the request-scoped logger in ctx, see logger.WithContext
+func WithLogger(ctx context.Context, l *zap.Logger) context.Context {
+	return logger.WithContext(ctx, l)
 }
 
+// GetLogger returns the request-scoped logger of ctx, or the global logger
 func GetLogger(ctx context.Context) *zap.Logger {
-	logger, ok := ctx.Value(loggerKey).(*zap.Logger)
-	if !ok {
-		return zap.L() // fallback to global logger
-	}
-	return logger
+	return logger.FromContext(ctx)
 }
 
 // MetricsMiddleware records HTTP request metrics using OpenTelemetry
@@ -108,34 +100,67 @@ func (m *middleware) MetricsMiddleware() gin.HandlerFunc {
 	}
 }
 
+// LoggingMiddleware logs every request with its headers and, where enabled, its
+// bodies. Secrets are masked and captured bodies are limited in size and to
+// textual content types. Bodies are captured while they stream through, so only
+// the part of the request body the handler reads is logged.
 func (m *middleware) LoggingMiddleware() gin.HandlerFunc {
+	cfg := m.config.HTTPLogging
+	// The link password and debug token headers are masked whatever the
+	// denylist says, as they are configurable and may have been renamed.
+	denylist := slices.Clone(cfg.HeaderDenylist)
+	for _, header := range []string{m.config.Password.Header, m.config.Log.DebugHeader} {
+		if header != "" {
+			denylist = append(denylist, header)
+		}
+	}
+	redactor := redact.New(redact.Config{
+		HeaderAllowlist: cfg.HeaderAllowlist,
+		HeaderDenylist:  denylist,
+		QueryParams:     cfg.QueryParams,
+		JSONFields:      cfg.JSONFields,
+		Mask:            cfg.Mask,
+	})
+	captureBodies := cfg.Bodies && (len(cfg.BodyEnvs) == 0 || slices.ContainsFunc(cfg.BodyEnvs, func(env string) bool {
+		return strings.EqualFold(env, m.config.Env)
+	}))
+	var sampler *requestSampler
+	if cfg.Sampling.Enabled {
+		sampler = newRequestSampler(cfg.Sampling.Initial, cfg.Sampling.Thereafter)
+	}
+
 	return func(c *gin.Context) {
 		start := time.Now()
-
-		// Capture request body
-		var requestBody []byte
-		if c.Request.Body != nil {
-			requestBody, _ = io.ReadAll(c.Request.Body)
-			// Reset the request body so subsequent handlers can read it normally
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		capture := captureBodies && !slices.Contains(cfg.SkipBodyRoutes, c.FullPath())
+
+		// Capture the start of the request body as the handler reads it
+		var requestCapture *bodyCapture
+		if capture && c.Request.Body != nil && c.Request.Body != http.NoBody && redact.TypeAllowed(c.GetHeader("Content-Type"), cfg.BodyContentTypes) {
+			requestCapture = newBodyCapture(cfg.MaxBodyBytes)
+			defer requestCapture.release()
+			c.Request.Body = &teeReadCloser{ReadCloser: c.Request.Body, capture: requestCapture}
 		}
 
-		// Create response capture writer
-		responseBody := &bytes.Buffer{}
-		responseWriter := &responseBodyWriter{
-			ResponseWriter: c.Writer,
-			body:           responseBody,
+		// Capture the start of the response body as it is written
+		var responseCapture *bodyCapture
+		if capture {
+			responseCapture = newBodyCapture(cfg.MaxBodyBytes)
+			defer responseCapture.release()
+			c.Writer = &teeResponseWriter{ResponseWriter: c.Writer, capture: responseCapture}
 		}
-		c.Writer = responseWriter
 
 		// Before handler execution
 		path := c.Request.URL.Path
+		query := redactor.Query(c.Request.URL.RawQuery)
 		method := c.Request.Method
 		traceID := ""
 		spanID := ""
+		id := requestID(c)
+		c.Header(RequestIDHeader, id)
 
 		// Extract trace info from context
-		if span := trace.SpanFromContext(c.Request.Context()); span != nil {
+		span := trace.SpanFromContext(c.Request.Context())
+		if span != nil {
 			sc := span.SpanContext()
 			if sc.HasTraceID() {
 				traceID = sc.TraceID().String()
@@ -143,12 +168,27 @@ func (m *middleware) LoggingMiddleware() gin.HandlerFunc {
 			if sc.HasSpanID() {
 				spanID = sc.SpanID().String()
 			}
-			span.SetAttributes(
-				attribute.String("http.request.body", string(requestBody)),
-			)
+			span.SetAttributes(attribute.String("http.request.id", id))
 		}
 
-		ctx := WithLogger(c.Request.Context(), m.logger)
+		// Every log line of the request, including those of the handlers and the
+		// core client, carries the fields needed to join it with its trace.
+		// Requests that ask for debug logging get a logger that ignores the global level.
+		requestLogger := m.logger
+		debugRequested := m.debugRequested(c)
+		if debugRequested {
+			requestLogger = logger.Debug()
+		}
+		requestLogger = requestLogger.With(
+			zap.String("trace_id", traceID),
+			zap.String("span_id", spanID),
+			zap.String("request_id", id),
+			zap.String("route", c.FullPath()),
+			zap.String("client_ip", c.ClientIP()),
+			zap.String("principal", principal.FromRequest(c.Request, c.ClientIP())),
+			logger.Context(c.Request.Context()),
+		)
+		ctx := WithLogger(c.Request.Context(), requestLogger)
 		c.Request = c.Request.WithContext(ctx)
 
 		c.Next()
@@ -157,40 +197,102 @@ func (m *middleware) LoggingMiddleware() gin.HandlerFunc {
 		latency := time.Since(start)
 		status := c.Writer.Status()
 
-		if span := trace.SpanFromContext(c.Request.Context()); span != nil {
-			span.SetAttributes(
-				attribute.String("http.response.body", responseBody.String()),
-			)
+		// Failed, slow and debug requests are always logged, others may be sampled
+		logged := sampler == nil || debugRequested || status >= http.StatusInternalServerError ||
+			len(c.Errors) > 0 || (cfg.Sampling.SlowThreshold > 0 && latency >= cfg.Sampling.SlowThreshold) || sampler.sample(start)
+		if !logged && !cfg.SpanBodies {
+			return
+		}
+
+		loggedRequestBody := ""
+		if requestCapture != nil {
+			loggedRequestBody = capturedBody(redactor, c.Request.Header.Get("Content-Type"), requestCapture)
+			if span != nil && cfg.SpanBodies {
+				span.SetAttributes(
+					attribute.String("http.request.body", loggedRequestBody),
+				)
+			}
 		}
 
-		// Log complete request and response information
-		m.logger.Info("HTTP request",
+		loggedResponseBody := ""
+		captureResponse := responseCapture != nil && redact.TypeAllowed(c.Writer.Header().Get("Content-Type"), cfg.BodyContentTypes)
+		if captureResponse {
+			loggedResponseBody = capturedBody(redactor, c.Writer.Header().Get("Content-Type"), responseCapture)
+			if span != nil && cfg.SpanBodies {
+				span.SetAttributes(
+					attribute.String("http.response.body", loggedResponseBody),
+				)
+			}
+		}
+
+		if !logged {
+			return
+		}
+
+		// Log request and response information
+		fields := []zap.Field{
 			zap.String("method", method),
 			zap.String("path", path),
+			zap.String("query", query),
 			zap.Int("status", status),
 			zap.Duration("latency_ms", latency),
-			zap.String("trace_id", traceID),
-			zap.String("span_id", spanID),
-			zap.String("request_headers", headersToString(c.Request.Header)),
-			zap.String("request_body", string(requestBody)),
-			zap.String("response_headers", headersToString(c.Writer.Header())),
-			zap.String("response_body", responseBody.String()),
-		)
+		}
+		if cfg.Headers {
+			fields = append(fields,
+				zap.String("request_headers", redactor.Headers(c.Request.Header)),
+				zap.String("response_headers", redactor.Headers(c.Writer.Header())),
+			)
+		}
+		if requestCapture != nil {
+			fields = append(fields,
+				zap.String("request_body", loggedRequestBody),
+				zap.Int64("request_body_bytes", requestCapture.total),
+				zap.Bool("request_body_truncated", requestCapture.Truncated()),
+			)
+		}
+		if captureResponse {
+			fields = append(fields,
+				zap.String("response_body", loggedResponseBody),
+				zap.Int64("response_body_bytes", responseCapture.total),
+				zap.Bool("response_body_truncated", responseCapture.Truncated()),
+			)
+		}
+		requestLogger.Info("HTTP request", fields...)
 	}
 }
 
-// headersToString converts HTTP headers to string
-func headersToString(headers http.Header) string {
-	buf := &bytes.Buffer{}
-	for name, values := range headers {
-		for _, value := range values {
-			buf.WriteString(name)
-			buf.WriteString(": ")
-			buf.WriteString(value)
-			buf.WriteString("\n")
+// debugRequested reports whether a request carries a valid debug token or the
+// debug baggage flag
+func (m *middleware) debugRequested(c *gin.Context) bool {
+	cfg := m.config.Log
+	if cfg.DebugHeader != "" {
+		if token := c.GetHeader(cfg.DebugHeader); token != "" && logger.VerifyDebugToken([]byte(cfg.DebugSecret), token, time.Now()) {
+			return true
 		}
 	}
-	return buf.String()
+	if cfg.DebugBaggageKey != "" {
+		switch strings.ToLower(baggage.FromContext(c.Request.Context()).Member(cfg.DebugBaggageKey).Value()) {
+		case "1", "true":
+			return true
+		}
+	}
+	return false
+}
+
+// capturedBody redacts a captured body and appends a marker when it was cut
+// off. A UTF-8 sequence split by the cut is dropped.
+func capturedBody(redactor *redact.Redactor, contentType string, capture *bodyCapture) string {
+	body := capture.Bytes()
+	if capture.Truncated() && len(body) > 0 {
+		start := len(body) - 1
+		for start > 0 && len(body)-start < utf8.UTFMax && !utf8.RuneStart(body[start]) {
+			start--
+		}
+		if !utf8.FullRune(body[start:]) {
+			body = body[:start]
+		}
+	}
+	return redactor.Body(contentType, body) + capture.Marker()
 }
 
 // RecoveryMiddleware captures panics, logs them with stack trace and returns 500 error
@@ -239,3 +341,95 @@ func (m *middleware) RecoveryMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AdminAuth only lets requests through that carry the configured admin token as a bearer token
+func (m *middleware) AdminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := m.config.Admin.Token
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin API is disabled"})
+			return
+		}
+
+		bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Idempotency stores the first response to a request carrying an idempotency key and
+// replays it to retries with the same key from the same caller. Concurrent retries
+// wait for the first request instead of running again, and reusing a key for a
+// different request, including a different query string, is rejected with 422.
+// Request bodies are read up to idempotency.max_request_bytes, and responses
+// larger than idempotency.max_response_bytes are not stored.
+func (m *middleware) Idempotency() gin.HandlerFunc {
+	cfg := m.config.Idempotency
+	store := idempotency.NewStore(cfg.TTL, cfg.MaxKeys)
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(cfg.Header)
+		if !cfg.Enabled || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > cfg.MaxKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency key is too long"})
+			return
+		}
+
+		var requestBody []byte
+		if c.Request.Body != nil {
+			var err error
+			requestBody, err = io.ReadAll(io.LimitReader(c.Request.Body, cfg.MaxRequestBytes+1))
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+				return
+			}
+			if int64(len(requestBody)) > cfg.MaxRequestBytes {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body is too large"})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		}
+		sum := sha256.Sum256(append([]byte(c.Request.Method+" "+c.Request.URL.Path+"?"+c.Request.URL.RawQuery+"\n"), requestBody...))
+
+		scope := principal.FromRequest(c.Request, c.ClientIP()) + "|" + key
+		resp, replayed, err := store.Do(c.Request.Context(), scope, hex.EncodeToString(sum[:]), func() idempotency.Response {
+			capture := newBodyCapture(cfg.MaxResponseBytes)
+			writer := &teeResponseWriter{ResponseWriter: c.Writer, capture: capture}
+			c.Writer = writer
+			// Restored before release, so that a recovered panic writes its
+			// response to the client rather than to the released capture
+			defer func() {
+				c.Writer = writer.ResponseWriter
+				capture.release()
+			}()
+			c.Next()
+			return idempotency.Response{
+				Status:      writer.Status(),
+				ContentType: writer.Header().Get("Content-Type"),
+				Body:        bytes.Clone(capture.Bytes()),
+				Discard:     capture.Truncated(),
+			}
+		})
+
+		switch {
+		case errors.Is(err, idempotency.ErrMismatch):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency key was already used for a different request"})
+		case errors.Is(err, idempotency.ErrFull):
+			GetLogger(c.Request.Context()).Warn("Idempotency store is full, handling request without deduplication")
+			c.Next()
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this idempotency key is still in progress"})
+		case replayed:
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(resp.Status, resp.ContentType, resp.Body)
+			c.Abort()
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the ID of a request. A valid ID sent by the caller
+// is kept, otherwise one is generated, and it is echoed in every response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds caller supplied request IDs
+const maxRequestIDLength = 128
+
+// requestID returns the request ID sent by the caller, or a new one
+func requestID(c *gin.Context) string {
+	if id := c.GetHeader(RequestIDHeader); validRequestID(id) {
+		return id
+	}
+
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID only accepts IDs that are safe to log and echo unescaped
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// requestSampler lets through the first initial requests of every second and
+// every thereafter-th request after that
+type requestSampler struct {
+	initial    uint64
+	thereafter uint64
+
+	second atomic.Int64
+	count  atomic.Uint64
+}
+
+func newRequestSampler(initial, thereafter int) *requestSampler {
+	return &requestSampler{
+		initial:    uint64(max(initial, 0)),
+		thereafter: uint64(max(thereafter, 0)),
+	}
+}
+
+// sample reports whether a request seen at now is logged
+func (s *requestSampler) sample(now time.Time) bool {
+	second := now.Unix()
+	if last := s.second.Load(); last != second && s.second.CompareAndSwap(last, second) {
+		s.count.Store(0)
+	}
+
+	n := s.count.Add(1)
+	if n <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.initial)%s.thereafter == 0
+}
@@ -0,0 +1,11 @@
+package model
+
+// LogLevelRequest changes the level of the gateway logger
+type LogLevelRequest struct {
+	Level string `json:"level" binding:"required" enums:"debug,info,warn,error"`
+}
+
+// DebugTokenRequest requests a token that turns on debug logging for requests carrying it
+type DebugTokenRequest struct {
+	ExpiresIn string `json:"expires_in" example:"15m"` // Go duration until the token expires
+}
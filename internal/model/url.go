@@ -1,6 +1,118 @@
 package model
 
+import "time"
+
+// MaxPasswordBytes is the longest link password, the input limit of bcrypt
+const MaxPasswordBytes = 72
+
+// Split variants are bounded, as their names become metric labels
+const (
+	MaxVariants          = 10 // most variants of a link
+	MaxVariantNameLength = 32 // longest variant name, made of letters, digits, '-', '_' and '.'
+)
+
 // ShortenRequest represents a request to shorten a URL
 type ShortenRequest struct {
-	OriginalURL string `json:"original_url"`
+	OriginalURL   string           `json:"original_url"`
+	Rules         []RedirectRule   `json:"rules,omitempty"`                               // optional targeting rules, evaluated in order
+	Variants      []Variant        `json:"variants,omitempty"`                            // optional A/B split destinations
+	Stickiness    Stickiness       `json:"stickiness,omitempty" enums:"none,cookie,hash"` // how variant assignment is kept per visitor
+	QRCode        bool             `json:"qr_code,omitempty"`                             // also return a PNG QR code as a data URI
+	Password      string           `json:"password,omitempty" maxLength:"72"`             // protect the link with a password, at most 72 bytes
+	Signed        *SignedOptions   `json:"signed,omitempty"`                              // only allow access through signed, expiring URLs
+	Interstitial  bool             `json:"interstitial,omitempty"`                        // show a warning page before redirecting
+	Redirect      *RedirectOptions `json:"redirect,omitempty"`                            // override the gateway's redirect defaults
+	UTM           *UTMOptions      `json:"utm,omitempty"`                                 // append utm_* parameters to the destinations
+	ReuseExisting bool             `json:"reuse_existing,omitempty"`                      // return the caller's existing link for the same URL and settings
+}
+
+// UTMOptions adds utm_* parameters to every destination URL of a new link.
+// Values set here take precedence over those of the preset.
+type UTMOptions struct {
+	Preset   string `json:"preset,omitempty" example:"newsletter"` // campaign preset defined in the gateway config
+	Source   string `json:"source,omitempty" example:"newsletter"`
+	Medium   string `json:"medium,omitempty" example:"email"`
+	Campaign string `json:"campaign,omitempty" example:"spring_sale"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+	Override bool   `json:"override,omitempty"` // replace utm_* parameters already present in a destination
+}
+
+// SignedOptions requests a signed, expiring URL for a new link
+type SignedOptions struct {
+	ExpiresIn string `json:"expires_in" example:"24h"` // Go duration until the signed URL expires
+}
+
+// Link is a short link as stored by the core service
+type Link struct {
+	ShortID     string
+	OriginalURL string // default destination when no rule matches and no variants are set
+	Owner       string // opaque ID of the caller that created the link, see principal.FromRequest
+	Rules       []RedirectRule
+	Variants    []Variant
+	Stickiness  Stickiness
+
+	// Password is only set when creating a link; the core stores a hash and
+	// reports PasswordProtected on expansion
+	Password          string
+	PasswordProtected bool
+
+	SignatureRequired bool // only signed URLs minted by the gateway may be expanded
+	Interstitial      bool // flagged as risky, visitors see a warning page before the redirect
+
+	CreatedAt time.Time // zero if the core did not report it
+
+	Redirect RedirectOptions // zero values use the gateway defaults
+}
+
+// RedirectOptions controls how visitors are sent to the destination
+type RedirectOptions struct {
+	Status   int          `json:"status,omitempty" enums:"301,302,307,308"`         // HTTP status of the redirect
+	Query    QueryMode    `json:"query,omitempty" enums:"off,keep,override,append"` // forward the short URL's query string
+	Fragment FragmentMode `json:"fragment,omitempty" enums:"destination,visitor"`   // whose fragment the visitor ends up on
+}
+
+// QueryMode controls whether and how the query string of the short URL is merged into the destination
+type QueryMode string
+
+const (
+	QueryOff      QueryMode = "off"      // do not forward the query string
+	QueryKeep     QueryMode = "keep"     // the destination's value wins for conflicting keys
+	QueryOverride QueryMode = "override" // the incoming value wins for conflicting keys
+	QueryAppend   QueryMode = "append"   // conflicting keys keep both values
+)
+
+// FragmentMode controls which fragment the visitor ends up on. Browsers never send
+// the fragment of the short URL, but carry it over when the redirect has none.
+type FragmentMode string
+
+const (
+	FragmentDestination FragmentMode = "destination" // keep the destination's fragment
+	FragmentVisitor     FragmentMode = "visitor"     // drop it so the short URL's fragment is carried over
+)
+
+// Stickiness controls whether a visitor keeps seeing the same split variant
+type Stickiness string
+
+const (
+	StickinessNone   Stickiness = "none"
+	StickinessCookie Stickiness = "cookie"
+	StickinessHash   Stickiness = "hash"
+)
+
+// Variant is one weighted destination of an A/B split link
+type Variant struct {
+	Name           string `json:"name" example:"B"` // up to 32 letters, digits, '-', '_' or '.'
+	DestinationURL string `json:"destination_url" example:"https://example.com/landing-b"`
+	Weight         uint32 `json:"weight" example:"50"`
+}
+
+// RedirectRule sends visitors matching all of its non-empty conditions to DestinationURL
+type RedirectRule struct {
+	Countries      []string   `json:"countries,omitempty" example:"US,CA"` // ISO 3166-1 alpha-2 codes
+	DeviceOS       []string   `json:"device_os,omitempty" example:"iOS"`   // client operating systems
+	Languages      []string   `json:"languages,omitempty" example:"en"`    // primary language subtags
+	NotBefore      *time.Time `json:"not_before,omitempty"`                // start of the active time window
+	NotAfter       *time.Time `json:"not_after,omitempty"`                 // end of the active time window
+	DestinationURL string     `json:"destination_url" example:"https://apps.apple.com/app/id0"`
 }
@@ -0,0 +1,10 @@
+package model
+
+// WebhookSubscriptionRequest represents a request to subscribe an endpoint to link events
+type WebhookSubscriptionRequest struct {
+	URL             string   `json:"url" example:"https://crm.example.com/hooks/shortlink"`
+	Events          []string `json:"events,omitempty" enums:"link.created,link.updated,link.expired,link.click_threshold"` // empty subscribes to every event
+	ClickThresholds []int64  `json:"click_thresholds,omitempty" example:"100,1000"`                                        // human click counts that trigger link.click_threshold, counted per gateway process
+	Secret          string   `json:"secret,omitempty"`                                                                     // HMAC key of the signatures, generated if empty
+	Description     string   `json:"description,omitempty"`
+}
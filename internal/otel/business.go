@@ -0,0 +1,100 @@
+package otel
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Redirect results
+const (
+	RedirectHit      = "hit"       // the visitor was sent on, or shown a page for the link
+	RedirectNotFound = "not_found" // the short ID does not exist
+	RedirectExpired  = "expired"   // the signed URL expired
+	RedirectBlocked  = "blocked"   // the destination is blocklisted
+	RedirectDenied   = "denied"    // password or signature missing or wrong
+	RedirectLoop     = "loop"      // the link chain loops back on itself
+	RedirectInvalid  = "invalid"   // the request was malformed
+	RedirectError    = "error"     // the link could not be resolved
+)
+
+// The methods below may be called on a nil *Metrics, which records nothing.
+// Every attribute has a bounded set of values: hosts and variant names are
+// capped by a TopK and short IDs are never used as labels.
+
+// LinkCreated counts a new short link by destination domain and the class of
+// the principal that created it
+func (m *Metrics) LinkCreated(ctx context.Context, destination, principalClass string) {
+	if m == nil {
+		return
+	}
+	m.LinksCreated.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("domain", m.linkDomains.Value(host(destination))),
+		attribute.String("principal_class", principalClass),
+	))
+}
+
+// Redirect counts a request for a short link by result. The destination host is
+// counted for hits only and may be empty when the visitor was not sent anywhere.
+func (m *Metrics) Redirect(ctx context.Context, result, destination string) {
+	if m == nil {
+		return
+	}
+	m.Redirects.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+	if result == RedirectHit && destination != "" {
+		m.RedirectDestinations.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("host", m.destinationHosts.Value(host(destination))),
+		))
+	}
+}
+
+// VariantClick counts a click on an A/B split variant. Variant names are chosen
+// by whoever creates a link, so only the most frequent get their own label.
+func (m *Metrics) VariantClick(ctx context.Context, variant string, bot bool) {
+	if m == nil {
+		return
+	}
+	m.VariantClicks.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("variant", m.variants.Value(variant)),
+		attribute.Bool("bot", bot),
+	))
+}
+
+// CoreCall records the latency and status code of a call to the core
+func (m *Metrics) CoreCall(ctx context.Context, method, code string, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	m.CoreRPCDuration.Record(ctx, latency.Seconds(), metric.WithAttributes(
+		attribute.String("rpc_method", method),
+		attribute.String("grpc_code", code),
+	))
+}
+
+// CacheLookup counts a hit or miss of the named cache
+func (m *Metrics) CacheLookup(ctx context.Context, cache string, hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.CacheRequests.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("cache", cache),
+		attribute.String("result", result),
+	))
+}
+
+// host returns the lower-cased host name of rawURL without its port
+func host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
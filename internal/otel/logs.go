@@ -0,0 +1,84 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hohotang/shortlink-gateway/internal/config"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// initLogs creates the log provider records are exported through, or nil when
+// exporting is disabled. The returned file, if any, is closed after the
+// provider has been shut down.
+func initLogs(ctx context.Context, cfg *config.Config, res *resource.Resource) (*sdklog.LoggerProvider, *os.File, error) {
+	export := cfg.Log.Export
+
+	var (
+		exporter sdklog.Exporter
+		file     *os.File
+		err      error
+	)
+	switch export.Exporter {
+	case "":
+		return nil, nil, nil
+	case "otlp":
+		endpoint := export.Endpoint
+		if endpoint == "" {
+			endpoint = cfg.OTLPEndpoint
+		}
+		options := []otlploghttp.Option{
+			otlploghttp.WithEndpoint(endpoint),
+			otlploghttp.WithTimeout(export.Timeout),
+		}
+		if export.Insecure {
+			options = append(options, otlploghttp.WithInsecure())
+		}
+		if len(export.Headers) > 0 {
+			options = append(options, otlploghttp.WithHeaders(export.Headers))
+		}
+		exporter, err = otlploghttp.New(ctx, options...)
+	case "stdout":
+		exporter, err = stdoutlog.New()
+	case "file":
+		if err := os.MkdirAll(filepath.Dir(export.FilePath), 0o755); err != nil {
+			return nil, nil, err
+		}
+		file, err = os.OpenFile(export.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporter, err = stdoutlog.New(stdoutlog.WithWriter(file))
+	default:
+		return nil, nil, fmt.Errorf("unknown log exporter %q", export.Exporter)
+	}
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		return nil, nil, err
+	}
+
+	// The batch processor never blocks logging: when the exporter falls behind
+	// and the queue is full, the oldest records are dropped
+	processor := sdklog.NewBatchProcessor(exporter,
+		sdklog.WithMaxQueueSize(export.MaxQueueSize),
+		sdklog.WithExportMaxBatchSize(export.BatchSize),
+		sdklog.WithExportInterval(export.Interval),
+		sdklog.WithExportTimeout(export.Timeout),
+	)
+	lp := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(processor),
+		sdklog.WithResource(res),
+	)
+
+	global.SetLoggerProvider(lp)
+	return lp, file, nil
+}
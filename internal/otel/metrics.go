@@ -0,0 +1,201 @@
+package otel
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+
+	"github.com/hohotang/shortlink-gateway/internal/config"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// initMetrics creates the meter provider with a Prometheus reader, a periodic
+// OTLP reader, or both. The Prometheus reader and the process collector are
+// registered in the returned registry, which is nil when Prometheus is disabled.
+func initMetrics(ctx context.Context, cfg *config.Config, res *resource.Resource) (*sdkmetric.MeterProvider, *prom.Registry, error) {
+	filter, err := exemplarFilter(cfg.Metrics.ExemplarFilter)
+	if err != nil {
+		return nil, nil, err
+	}
+	options := []sdkmetric.Option{
+		sdkmetric.WithResource(res),
+		sdkmetric.WithExemplarFilter(filter),
+	}
+
+	var registry *prom.Registry
+	if cfg.Metrics.Prometheus {
+		registry = prom.NewRegistry()
+		if err := registry.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})); err != nil {
+			return nil, nil, err
+		}
+		exporter, err := prometheus.New(prometheus.WithRegisterer(registry))
+		if err != nil {
+			return nil, nil, err
+		}
+		options = append(options, sdkmetric.WithReader(exporter))
+	}
+
+	if cfg.Metrics.OTLP.Enabled {
+		exporter, err := newMetricExporter(ctx, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		options = append(options, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter,
+			sdkmetric.WithInterval(cfg.Metrics.OTLP.Interval),
+			sdkmetric.WithTimeout(cfg.Metrics.OTLP.Timeout),
+		)))
+	}
+
+	mp := sdkmetric.NewMeterProvider(options...)
+
+	otel.SetMeterProvider(mp)
+	return mp, registry, nil
+}
+
+// newMetricExporter creates the OTLP metric exporter
+func newMetricExporter(ctx context.Context, cfg *config.Config) (sdkmetric.Exporter, error) {
+	export := cfg.Metrics.OTLP
+
+	endpoint := export.Endpoint
+	if endpoint == "" {
+		endpoint = cfg.MetricsEndpoint
+	}
+
+	temporality, err := temporalitySelector(export.Temporality)
+	if err != nil {
+		return nil, err
+	}
+	aggregation, err := aggregationSelector(export.HistogramAggregation)
+	if err != nil {
+		return nil, err
+	}
+
+	var tlsConfig *tls.Config
+	if !export.Insecure {
+		if tlsConfig, err = newTLSConfig(export.TLS); err != nil {
+			return nil, err
+		}
+	}
+
+	switch export.Compression {
+	case "none", "", "gzip":
+	default:
+		return nil, fmt.Errorf("unknown metric compression %q", export.Compression)
+	}
+
+	switch export.Protocol {
+	case "http", "":
+		options := []otlpmetrichttp.Option{
+			otlpmetrichttp.WithEndpoint(endpoint),
+			otlpmetrichttp.WithTimeout(export.Timeout),
+			otlpmetrichttp.WithTemporalitySelector(temporality),
+			otlpmetrichttp.WithAggregationSelector(aggregation),
+		}
+		if export.URLPath != "" {
+			options = append(options, otlpmetrichttp.WithURLPath(export.URLPath))
+		}
+		if tlsConfig == nil {
+			options = append(options, otlpmetrichttp.WithInsecure())
+		} else {
+			options = append(options, otlpmetrichttp.WithTLSClientConfig(tlsConfig))
+		}
+		if len(export.Headers) > 0 {
+			options = append(options, otlpmetrichttp.WithHeaders(export.Headers))
+		}
+		if export.Compression == "gzip" {
+			options = append(options, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+		}
+		return otlpmetrichttp.New(ctx, options...)
+	case "grpc":
+		options := []otlpmetricgrpc.Option{
+			otlpmetricgrpc.WithEndpoint(endpoint),
+			otlpmetricgrpc.WithTimeout(export.Timeout),
+			otlpmetricgrpc.WithTemporalitySelector(temporality),
+			otlpmetricgrpc.WithAggregationSelector(aggregation),
+		}
+		if tlsConfig == nil {
+			options = append(options, otlpmetricgrpc.WithInsecure())
+		} else {
+			options = append(options, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+		}
+		if len(export.Headers) > 0 {
+			options = append(options, otlpmetricgrpc.WithHeaders(export.Headers))
+		}
+		if export.Compression == "gzip" {
+			options = append(options, otlpmetricgrpc.WithCompressor(gzip.Name))
+		}
+		return otlpmetricgrpc.New(ctx, options...)
+	}
+	return nil, fmt.Errorf("unknown OTLP protocol %q", export.Protocol)
+}
+
+// temporalitySelector returns the temporality of each instrument kind:
+// cumulative for everything, delta for all but up-down counters, or delta
+// only for synchronous counters and histograms (lowmemory)
+func temporalitySelector(name string) (sdkmetric.TemporalitySelector, error) {
+	switch name {
+	case "cumulative", "":
+		return sdkmetric.DefaultTemporalitySelector, nil
+	case "delta":
+		return func(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+			switch kind {
+			case sdkmetric.InstrumentKindUpDownCounter, sdkmetric.InstrumentKindObservableUpDownCounter:
+				return metricdata.CumulativeTemporality
+			}
+			return metricdata.DeltaTemporality
+		}, nil
+	case "lowmemory":
+		return func(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+			switch kind {
+			case sdkmetric.InstrumentKindCounter, sdkmetric.InstrumentKindHistogram:
+				return metricdata.DeltaTemporality
+			}
+			return metricdata.CumulativeTemporality
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown metric temporality %q", name)
+}
+
+// aggregationSelector returns the aggregation of each instrument kind, with
+// histograms using explicit buckets or base2 exponential buckets
+func aggregationSelector(name string) (sdkmetric.AggregationSelector, error) {
+	switch name {
+	case "explicit", "":
+		return sdkmetric.DefaultAggregationSelector, nil
+	case "exponential":
+		return func(kind sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+			if kind == sdkmetric.InstrumentKindHistogram {
+				return sdkmetric.AggregationBase2ExponentialHistogram{MaxSize: 160, MaxScale: 20}
+			}
+			return sdkmetric.DefaultAggregationSelector(kind)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown histogram aggregation %q", name)
+}
+
+// exemplarFilter returns the filter deciding which measurements are kept as
+// exemplars. trace_based keeps those recorded in a sampled span, so that a
+// histogram bucket links to a trace.
+func exemplarFilter(name string) (exemplar.Filter, error) {
+	switch name {
+	case "trace_based", "":
+		return exemplar.TraceBasedFilter, nil
+	case "always_on":
+		return exemplar.AlwaysOnFilter, nil
+	case "always_off":
+		return exemplar.AlwaysOffFilter, nil
+	}
+	return nil, fmt.Errorf("unknown exemplar filter %q", name)
+}
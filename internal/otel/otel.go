@@ -2,15 +2,16 @@ package otel
 
 import (
 	"context"
+	"os"
 	"time"
 
 	"github.com/hohotang/shortlink-gateway/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
-	"go.opentelemetry.io/otel/exporters/prometheus"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
@@ -22,38 +23,70 @@ import (
 type Telemetry struct {
 	TracerProvider *trace.TracerProvider
 	MeterProvider  *sdkmetric.MeterProvider
+	LoggerProvider *sdklog.LoggerProvider // nil when log export is disabled
+	Registry       *prometheus.Registry   // everything served on /metrics, nil when Prometheus is disabled
 	Metrics        *Metrics
 	Logger         *zap.Logger
+
+	traceFile *os.File
+	logFile   *os.File
 }
 
 // Metrics contains all metric instruments
 type Metrics struct {
 	RequestCounter  metric.Int64Counter
 	RequestDuration metric.Float64Histogram
+	ClickCounter    metric.Int64Counter
+	UniqueVisitors  metric.Int64Counter
+	VariantClicks   metric.Int64Counter
+	BlockDecisions  metric.Int64Counter
+	// Webhook metrics
+	WebhookDeliveries metric.Int64Counter
+	// Business metrics, see business.go
+	LinksCreated         metric.Int64Counter
+	Redirects            metric.Int64Counter
+	RedirectDestinations metric.Int64Counter
+	CoreRPCDuration      metric.Float64Histogram
+	CacheRequests        metric.Int64Counter
+
+	linkDomains      *TopK
+	destinationHosts *TopK
+	variants         *TopK
 }
 
 // New creates a new Telemetry instance with all components initialized
 func New(ctx context.Context, cfg *config.Config, logger *zap.Logger) (*Telemetry, error) {
-	// Create a shared resource for both trace and metrics
+	// Create a shared resource for traces, metrics and logs
 	res := resource.NewWithAttributes(
 		semconv.SchemaURL,
 		semconv.ServiceName(cfg.ServiceName),
 	)
 
 	// Initialize tracing
-	tp, err := initTracing(ctx, cfg, res)
+	tp, traceFile, err := initTracing(ctx, cfg, res)
 	if err != nil {
 		return nil, err
 	}
 
 	// Initialize metrics
-	mp, err := initMetrics(res)
+	mp, registry, err := initMetrics(ctx, cfg, res)
 	if err != nil {
 		return nil, err
 	}
 
+	// Go runtime and build metrics
+	if err := initRuntimeMetrics(mp); err != nil {
+		return nil, err
+	}
+
 	// Initialize metric instruments
-	metrics, err := initMetricInstruments(mp)
+	metrics, err := initMetricInstruments(mp, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize log export
+	lp, logFile, err := initLogs(ctx, cfg, res)
 	if err != nil {
 		return nil, err
 	}
@@ -67,67 +100,137 @@ func New(ctx context.Context, cfg *config.Config, logger *zap.Logger) (*Telemetr
 	return &Telemetry{
 		TracerProvider: tp,
 		MeterProvider:  mp,
+		LoggerProvider: lp,
+		Registry:       registry,
 		Metrics:        metrics,
 		Logger:         logger,
+		traceFile:      traceFile,
+		logFile:        logFile,
 	}, nil
 }
 
-func initTracing(ctx context.Context, cfg *config.Config, res *resource.Resource) (*trace.TracerProvider, error) {
-	exporter, err := otlptracehttp.New(ctx,
-		otlptracehttp.WithEndpoint(cfg.TracesEndpoint),
-		otlptracehttp.WithInsecure(),
+func initMetricInstruments(mp *sdkmetric.MeterProvider, cfg *config.Config) (*Metrics, error) {
+	meter := mp.Meter("http-server")
+
+	requestCounter, err := meter.Int64Counter(
+		"http_server_requests_total",
+		metric.WithDescription("Total number of HTTP requests"),
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	tp := trace.NewTracerProvider(
-		trace.WithBatcher(exporter),
-		trace.WithResource(res),
+	requestDuration, err := meter.Float64Histogram(
+		"http_server_request_duration_seconds",
+		metric.WithDescription("Duration of HTTP requests in seconds"),
+		metric.WithUnit("s"),
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	otel.SetTracerProvider(tp)
-	return tp, nil
-}
+	linkMeter := mp.Meter("shortlink")
 
-func initMetrics(res *resource.Resource) (*sdkmetric.MeterProvider, error) {
-	exporter, err := prometheus.New(prometheus.WithRegisterer(nil))
+	clickCounter, err := linkMeter.Int64Counter(
+		"shortlink_clicks_total",
+		metric.WithDescription("Total number of short link clicks, labelled by bot classification"),
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	mp := sdkmetric.NewMeterProvider(
-		sdkmetric.WithReader(exporter),
-		sdkmetric.WithResource(res),
+	uniqueVisitors, err := linkMeter.Int64Counter(
+		"shortlink_unique_visitors_total",
+		metric.WithDescription("Number of first-seen human visitors per short link, excluding bots and prefetches"),
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	otel.SetMeterProvider(mp)
-	return mp, nil
-}
+	variantClicks, err := linkMeter.Int64Counter(
+		"shortlink_variant_clicks_total",
+		metric.WithDescription("Total number of clicks per A/B split variant"),
+	)
+	if err != nil {
+		return nil, err
+	}
 
-func initMetricInstruments(mp *sdkmetric.MeterProvider) (*Metrics, error) {
-	meter := mp.Meter("http-server")
+	blockDecisions, err := linkMeter.Int64Counter(
+		"shortlink_blocklist_decisions_total",
+		metric.WithDescription("Total number of destination URLs checked against the blocklist, labelled by stage, result and reason"),
+	)
+	if err != nil {
+		return nil, err
+	}
 
-	requestCounter, err := meter.Int64Counter(
-		"http_server_requests_total",
-		metric.WithDescription("Total number of HTTP requests"),
+	webhookDeliveries, err := linkMeter.Int64Counter(
+		"shortlink_webhook_deliveries_total",
+		metric.WithDescription("Total number of webhook delivery outcomes, labelled by event and result (succeeded, retried or dead)"),
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	requestDuration, err := meter.Float64Histogram(
-		"http_server_request_duration_seconds",
-		metric.WithDescription("Duration of HTTP requests in seconds"),
+	linksCreated, err := linkMeter.Int64Counter(
+		"shortlink_links_created_total",
+		metric.WithDescription("Total number of short links created, labelled by destination domain (top hosts only) and principal class"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	redirects, err := linkMeter.Int64Counter(
+		"shortlink_redirects_total",
+		metric.WithDescription("Total number of short link requests, labelled by result (hit, not_found, expired, blocked, denied, loop, invalid or error)"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	redirectDestinations, err := linkMeter.Int64Counter(
+		"shortlink_redirect_destinations_total",
+		metric.WithDescription("Total number of visitors sent to each destination host, top hosts only"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	coreRPCDuration, err := linkMeter.Float64Histogram(
+		"shortlink_core_rpc_duration_seconds",
+		metric.WithDescription("Duration of calls to the core service in seconds, labelled by method and gRPC status code"),
 		metric.WithUnit("s"),
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	cacheRequests, err := linkMeter.Int64Counter(
+		"shortlink_cache_requests_total",
+		metric.WithDescription("Total number of cache lookups, labelled by cache (link or qr) and result (hit or miss)"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Metrics{
 		RequestCounter:  requestCounter,
 		RequestDuration: requestDuration,
+		ClickCounter:    clickCounter,
+		UniqueVisitors:  uniqueVisitors,
+		VariantClicks:   variantClicks,
+		BlockDecisions:  blockDecisions,
+
+		WebhookDeliveries: webhookDeliveries,
+
+		LinksCreated:         linksCreated,
+		Redirects:            redirects,
+		RedirectDestinations: redirectDestinations,
+		CoreRPCDuration:      coreRPCDuration,
+		CacheRequests:        cacheRequests,
+
+		linkDomains:      NewTopK(cfg.Metrics.TopHosts, cfg.Metrics.TopHostsMinCount, cfg.Metrics.TopHostsLabels),
+		destinationHosts: NewTopK(cfg.Metrics.TopHosts, cfg.Metrics.TopHostsMinCount, cfg.Metrics.TopHostsLabels),
+		variants:         NewTopK(cfg.Metrics.TopVariants, cfg.Metrics.TopHostsMinCount, cfg.Metrics.TopHostsLabels),
 	}, nil
 }
 
@@ -146,6 +249,12 @@ func (t *Telemetry) Shutdown(ctx context.Context) error {
 		}
 	}
 
+	if t.traceFile != nil {
+		if closeErr := t.traceFile.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
 	// Shutdown metric provider
 	if t.MeterProvider != nil {
 		mpCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -157,5 +266,21 @@ func (t *Telemetry) Shutdown(ctx context.Context) error {
 		}
 	}
 
+	// Shutdown log provider, flushing the records still queued
+	if t.LoggerProvider != nil {
+		lpCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if shutdownErr := t.LoggerProvider.Shutdown(lpCtx); shutdownErr != nil && err == nil {
+			t.Logger.Error("Failed to shut down log provider",
+				zap.Error(shutdownErr))
+			err = shutdownErr
+		}
+	}
+	if t.logFile != nil {
+		if closeErr := t.logFile.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
 	return err
 }
@@ -0,0 +1,35 @@
+package otel
+
+import (
+	"context"
+
+	"github.com/hohotang/shortlink-gateway/internal/version"
+
+	"go.opentelemetry.io/contrib/instrumentation/runtime"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// initRuntimeMetrics starts the Go runtime instrumentation (memory, GC,
+// goroutines) and exports the build as a constant shortlink_build_info gauge
+func initRuntimeMetrics(mp *sdkmetric.MeterProvider) error {
+	if err := runtime.Start(runtime.WithMeterProvider(mp)); err != nil {
+		return err
+	}
+
+	attrs := metric.WithAttributes(
+		attribute.String("version", version.Version),
+		attribute.String("commit", version.Commit),
+		attribute.String("go_version", version.GoVersion),
+	)
+	_, err := mp.Meter("shortlink").Int64ObservableGauge(
+		"shortlink_build_info",
+		metric.WithDescription("Always 1, labelled by the version, commit and Go version of the running build"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(1, attrs)
+			return nil
+		}),
+	)
+	return err
+}
@@ -0,0 +1,124 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attributes the rule sampler looks at. Depending on the semantic
+// convention version in use the path is in url.path or http.target.
+const (
+	routeKey      = attribute.Key("http.route")
+	urlPathKey    = attribute.Key("url.path")
+	httpTargetKey = attribute.Key("http.target")
+	statusKey     = attribute.Key("http.response.status_code")
+	oldStatusKey  = attribute.Key("http.status_code")
+)
+
+// ruleSampler applies path and route rules before falling back to a ratio
+// sampler. Spans the fallback drops can still be recorded, so that
+// keepSpanProcessor exports them when they fail or are slow. Recording them
+// costs as much as sampling them, only their export is saved.
+type ruleSampler struct {
+	neverPaths      []string
+	alwaysRoutes    []string
+	fallback        sdktrace.Sampler
+	recordUnsampled bool
+}
+
+func (s ruleSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	var path, route string
+	for _, attr := range p.Attributes {
+		switch attr.Key {
+		case urlPathKey:
+			path = attr.Value.AsString()
+		case httpTargetKey:
+			if path == "" {
+				path, _, _ = strings.Cut(attr.Value.AsString(), "?")
+			}
+		case routeKey:
+			route = attr.Value.AsString()
+		}
+	}
+
+	psc := trace.SpanContextFromContext(p.ParentContext)
+	if path != "" && slices.ContainsFunc(s.neverPaths, func(prefix string) bool { return strings.HasPrefix(path, prefix) }) {
+		return sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: psc.TraceState()}
+	}
+	if route != "" && slices.Contains(s.alwaysRoutes, route) {
+		return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSample, Tracestate: psc.TraceState()}
+	}
+
+	result := s.fallback.ShouldSample(p)
+	if result.Decision == sdktrace.Drop && s.recordUnsampled {
+		result.Decision = sdktrace.RecordOnly
+	}
+	return result
+}
+
+func (s ruleSampler) Description() string {
+	return fmt.Sprintf("RuleSampler{never=%v,always=%v,fallback=%s}", s.neverPaths, s.alwaysRoutes, s.fallback.Description())
+}
+
+// keepSpanProcessor passes sampled spans on to next, as well as recorded but
+// unsampled spans that ended with an error or took longer than slow
+type keepSpanProcessor struct {
+	next       sdktrace.SpanProcessor
+	keepErrors bool
+	slow       time.Duration
+}
+
+func (p keepSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+func (p keepSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		p.next.OnEnd(s)
+		return
+	}
+	if p.keep(s) {
+		p.next.OnEnd(keptSpan{s})
+	}
+}
+
+func (p keepSpanProcessor) keep(s sdktrace.ReadOnlySpan) bool {
+	if p.keepErrors {
+		if s.Status().Code == codes.Error {
+			return true
+		}
+		for _, attr := range s.Attributes() {
+			if (attr.Key == statusKey || attr.Key == oldStatusKey) && attr.Value.AsInt64() >= 500 {
+				return true
+			}
+		}
+	}
+	return p.slow > 0 && s.EndTime().Sub(s.StartTime()) >= p.slow
+}
+
+func (p keepSpanProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+func (p keepSpanProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// keptSpan marks an unsampled span as sampled, so that the batch processor
+// exports it
+type keptSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (s keptSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
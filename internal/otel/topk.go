@@ -0,0 +1,96 @@
+package otel
+
+import "sync"
+
+// OtherValue replaces attribute values that are not in the top K
+const OtherValue = "other"
+
+// TopK caps the number of distinct values of a metric attribute to the k most
+// frequent ones, using the Space-Saving algorithm: k counters are kept, and an
+// unknown value takes over the counter of the least frequent one, starting
+// from its count. A value that grows more frequent than others therefore
+// replaces them, even after k values have been seen.
+//
+// A value has its own label while it is counted and has been seen minCount
+// times since it took over its counter, so that the one-off values cycling
+// through the least frequent counter during a flood are reported as OtherValue.
+// As exported series never expire, at most maxLabels distinct values are ever
+// labelled; values reaching the top after that are reported as OtherValue too.
+type TopK struct {
+	mu        sync.Mutex
+	k         int
+	minCount  uint64
+	maxLabels int
+	counters  map[string]*topKCounter
+	labelled  map[string]struct{}
+}
+
+// topKCounter counts a value. count overestimates its frequency by at most
+// overestimate, the count of the value it replaced.
+type topKCounter struct {
+	count        uint64
+	overestimate uint64
+}
+
+// NewTopK creates a TopK labelling at most k values at a time and maxLabels
+// values in total
+func NewTopK(k, minCount, maxLabels int) *TopK {
+	return &TopK{
+		k:         max(k, 0),
+		minCount:  uint64(max(minCount, 1)),
+		maxLabels: max(maxLabels, 0),
+		counters:  make(map[string]*topKCounter, max(k, 0)),
+		labelled:  make(map[string]struct{}),
+	}
+}
+
+// Value counts v and returns it if it has its own label, and OtherValue otherwise
+func (t *TopK) Value(v string) string {
+	if v == "" || t.k == 0 {
+		return OtherValue
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	counter, ok := t.counters[v]
+	switch {
+	case ok:
+	case len(t.counters) < t.k:
+		counter = &topKCounter{}
+		t.counters[v] = counter
+	default:
+		counter = t.evict()
+		t.counters[v] = counter
+	}
+	counter.count++
+
+	if counter.count-counter.overestimate < t.minCount {
+		return OtherValue
+	}
+	if _, ok := t.labelled[v]; !ok {
+		if len(t.labelled) >= t.maxLabels {
+			return OtherValue
+		}
+		t.labelled[v] = struct{}{}
+	}
+	return v
+}
+
+// evict removes the least frequent value and returns its counter, reset to
+// count from there for the value replacing it. k is small, so a scan is cheaper
+// than keeping the counters ordered on every call.
+func (t *TopK) evict() *topKCounter {
+	var (
+		minValue   string
+		minCounter *topKCounter
+	)
+	for value, counter := range t.counters {
+		if minCounter == nil || counter.count < minCounter.count {
+			minValue, minCounter = value, counter
+		}
+	}
+	delete(t.counters, minValue)
+	minCounter.overestimate = minCounter.count
+	return minCounter
+}
@@ -0,0 +1,190 @@
+package otel
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hohotang/shortlink-gateway/internal/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// initTracing creates the tracer provider. The returned file, if any, is
+// closed after the provider has been shut down.
+func initTracing(ctx context.Context, cfg *config.Config, res *resource.Resource) (*trace.TracerProvider, *os.File, error) {
+	tracing := cfg.Tracing
+	sampling := tracing.Sampling
+
+	var sampler trace.Sampler = trace.TraceIDRatioBased(sampling.Ratio)
+	if sampling.ParentBased {
+		sampler = trace.ParentBased(sampler)
+	}
+	keepUnsampled := sampling.KeepErrors || sampling.SlowThreshold > 0
+	sampler = ruleSampler{
+		neverPaths:      sampling.NeverPaths,
+		alwaysRoutes:    sampling.AlwaysRoutes,
+		fallback:        sampler,
+		recordUnsampled: keepUnsampled,
+	}
+	options := []trace.TracerProviderOption{
+		trace.WithSampler(sampler),
+		trace.WithResource(res),
+	}
+
+	exporter, file, err := newTraceExporter(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if exporter != nil {
+		var processor trace.SpanProcessor = trace.NewBatchSpanProcessor(exporter,
+			trace.WithMaxQueueSize(tracing.Batch.MaxQueueSize),
+			trace.WithMaxExportBatchSize(tracing.Batch.MaxExportSize),
+			trace.WithBatchTimeout(tracing.Batch.Timeout),
+			trace.WithExportTimeout(tracing.Timeout),
+		)
+		if keepUnsampled {
+			processor = keepSpanProcessor{
+				next:       processor,
+				keepErrors: sampling.KeepErrors,
+				slow:       sampling.SlowThreshold,
+			}
+		}
+		options = append(options, trace.WithSpanProcessor(processor))
+	}
+
+	tp := trace.NewTracerProvider(options...)
+
+	otel.SetTracerProvider(tp)
+	return tp, file, nil
+}
+
+// newTraceExporter creates the configured span exporter, or nil for none
+func newTraceExporter(ctx context.Context, cfg *config.Config) (trace.SpanExporter, *os.File, error) {
+	tracing := cfg.Tracing
+
+	endpoint := tracing.Endpoint
+	if endpoint == "" {
+		endpoint = cfg.TracesEndpoint
+	}
+
+	var tlsConfig *tls.Config
+	if tracing.Exporter == "otlp" && !tracing.Insecure {
+		var err error
+		if tlsConfig, err = newTLSConfig(tracing.TLS); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	switch tracing.Exporter {
+	case "none", "":
+		return nil, nil, nil
+	case "stdout":
+		exporter, err := stdouttrace.New()
+		return exporter, nil, err
+	case "file":
+		if err := os.MkdirAll(filepath.Dir(tracing.FilePath), 0o755); err != nil {
+			return nil, nil, err
+		}
+		file, err := os.OpenFile(tracing.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		return exporter, file, nil
+	case "otlp":
+	default:
+		return nil, nil, fmt.Errorf("unknown trace exporter %q", tracing.Exporter)
+	}
+
+	switch tracing.Compression {
+	case "none", "", "gzip":
+	default:
+		return nil, nil, fmt.Errorf("unknown trace compression %q", tracing.Compression)
+	}
+
+	switch tracing.Protocol {
+	case "http", "":
+		options := []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(endpoint),
+			otlptracehttp.WithTimeout(tracing.Timeout),
+		}
+		if tlsConfig == nil {
+			options = append(options, otlptracehttp.WithInsecure())
+		} else {
+			options = append(options, otlptracehttp.WithTLSClientConfig(tlsConfig))
+		}
+		if len(tracing.Headers) > 0 {
+			options = append(options, otlptracehttp.WithHeaders(tracing.Headers))
+		}
+		if tracing.Compression == "gzip" {
+			options = append(options, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		}
+		exporter, err := otlptracehttp.New(ctx, options...)
+		return exporter, nil, err
+	case "grpc":
+		options := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(endpoint),
+			otlptracegrpc.WithTimeout(tracing.Timeout),
+		}
+		if tlsConfig == nil {
+			options = append(options, otlptracegrpc.WithInsecure())
+		} else {
+			options = append(options, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+		}
+		if len(tracing.Headers) > 0 {
+			options = append(options, otlptracegrpc.WithHeaders(tracing.Headers))
+		}
+		if tracing.Compression == "gzip" {
+			options = append(options, otlptracegrpc.WithCompressor(gzip.Name))
+		}
+		exporter, err := otlptracegrpc.New(ctx, options...)
+		return exporter, nil, err
+	}
+	return nil, nil, fmt.Errorf("unknown OTLP protocol %q", tracing.Protocol)
+}
+
+// newTLSConfig builds a client TLS configuration from PEM files
+func newTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in " + cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
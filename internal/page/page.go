@@ -0,0 +1,79 @@
+package page
+
+import (
+	"embed"
+	"html/template"
+	"path/filepath"
+	"time"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// Templates parses the HTML pages served by the gateway instead of a plain redirect.
+// When dir is set, its *.html files are parsed on top so they replace built-in
+// pages and partials of the same name.
+func Templates(dir string) (*template.Template, error) {
+	tmpl := template.Must(template.New("").ParseFS(templateFS, "templates/*.html"))
+	if dir == "" {
+		return tmpl, nil
+	}
+
+	overrides, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil || len(overrides) == 0 {
+		return tmpl, err
+	}
+	return tmpl.ParseFiles(overrides...)
+}
+
+// Theme customises the look of the pages, it is passed to the "theme" and "brand" partials
+type Theme struct {
+	BrandName       string
+	LogoURL         string
+	AccentColor     string
+	BackgroundColor string
+	TextColor       string
+	StylesheetURL   string
+}
+
+// UnfurlData is rendered for link unfurlers that should receive metadata instead of a redirect
+type UnfurlData struct {
+	ShortURL       string
+	DestinationURL string
+	Host           string
+}
+
+// PasswordData is rendered as the challenge page of a password protected link
+type PasswordData struct {
+	Theme  Theme
+	Action string // URL the password form posts to
+	Error  string
+	Locked bool // too many attempts, the form is disabled
+}
+
+// PreviewData is rendered for short IDs with a "+" suffix instead of redirecting
+type PreviewData struct {
+	Theme          Theme
+	ShortURL       string
+	DestinationURL string // default destination of the link
+	ContinueURL    string // the short URL without the suffix
+	Targeted       bool   // rules or split variants may pick another destination
+	CreatedAt      time.Time
+	Clicks         int64
+}
+
+// InterstitialData is rendered as the warning page shown before leaving to the destination
+type InterstitialData struct {
+	Theme          Theme
+	DestinationURL string
+	Host           string
+	Reason         string // "external" or "flagged"
+	Countdown      int    // seconds until the automatic redirect, 0 disables it
+}
+
+// BlockedData is rendered instead of redirecting to a blocked destination
+type BlockedData struct {
+	Theme  Theme
+	Host   string
+	Reason string // blocklist reason code
+}
@@ -0,0 +1,34 @@
+package principal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// credentialHeaders are checked in order for a caller credential
+var credentialHeaders = []string{"Authorization", "AccessToken"}
+
+// Anonymous prefixes the principal of callers without credentials
+const Anonymous = "anonymous"
+
+// FromRequest identifies the caller of r without keeping its credentials: a
+// hash of the Authorization or AccessToken header, or the client IP for
+// anonymous callers
+func FromRequest(r *http.Request, clientIP string) string {
+	for _, header := range credentialHeaders {
+		if credential := r.Header.Get(header); credential != "" {
+			sum := sha256.Sum256([]byte(credential))
+			return "token:" + hex.EncodeToString(sum[:12])
+		}
+	}
+	return Anonymous + ":" + clientIP
+}
+
+// Class returns the kind of a principal returned by FromRequest, "token" or
+// "anonymous", which unlike the principal itself is fit for a metric label
+func Class(principal string) string {
+	class, _, _ := strings.Cut(principal, ":")
+	return class
+}
@@ -0,0 +1,193 @@
+package qr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Format is the output image format of a QR code
+type Format string
+
+const (
+	FormatPNG Format = "png"
+	FormatSVG Format = "svg"
+)
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	if f == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Options controls how a QR code is rendered
+type Options struct {
+	Format     Format
+	Size       int    // width and height of the image in pixels
+	Level      string // error correction level: L, M, Q or H
+	Margin     int    // quiet zone around the code, in modules
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+// ETag returns a strong entity tag identifying the image rendered for content with these options
+func (o Options) ETag(content string) string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%s|%d|%s|%s",
+		content, o.Format, o.Size, o.Level, o.Margin, HexColor(o.Foreground), HexColor(o.Background))))
+	return `"` + hex.EncodeToString(h[:16]) + `"`
+}
+
+// ErrContentTooLong is returned by Render when content does not fit in a QR code
+var ErrContentTooLong = errors.New("content too long for a QR code")
+
+// ValidateLevel checks an error correction level
+func ValidateLevel(level string) error {
+	_, err := recoveryLevel(level)
+	return err
+}
+
+// Render encodes content as a QR code image
+func Render(content string, opts Options) ([]byte, error) {
+	level, err := recoveryLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	// With a valid level the encoder only fails when content is too long
+	code, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrContentTooLong, err)
+	}
+	code.DisableBorder = true // the margin is drawn below so its width is configurable
+
+	modules := code.Bitmap()
+
+	switch opts.Format {
+	case FormatSVG:
+		return renderSVG(modules, opts), nil
+	case FormatPNG, "":
+		return renderPNG(modules, opts)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", opts.Format)
+	}
+}
+
+// DataURI renders content and returns it as a base64 data URI for inline embedding
+func DataURI(content string, opts Options) (string, error) {
+	img, err := Render(content, opts)
+	if err != nil {
+		return "", err
+	}
+	return "data:" + opts.Format.ContentType() + ";base64," + base64.StdEncoding.EncodeToString(img), nil
+}
+
+// renderPNG draws the modules with an integer scale, centering the code when the
+// requested size is not an exact multiple of the module count
+func renderPNG(modules [][]bool, opts Options) ([]byte, error) {
+	total := len(modules) + 2*opts.Margin
+	scale := opts.Size / total
+	if scale < 1 {
+		scale = 1
+	}
+	size := opts.Size
+	if size < total*scale {
+		size = total * scale
+	}
+	offset := (size-total*scale)/2 + opts.Margin*scale
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{opts.Background, opts.Foreground})
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(offset+x*scale+dx, offset+y*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderSVG emits a single path with one unit square per dark module
+func renderSVG(modules [][]bool, opts Options) []byte {
+	total := len(modules) + 2*opts.Margin
+
+	var path strings.Builder
+	for y, row := range modules {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x+opts.Margin, y+opts.Margin)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, total, total)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`, HexColor(opts.Background))
+	fmt.Fprintf(&buf, `<path fill="%s" d="%s"/>`, HexColor(opts.Foreground), path.String())
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
+
+func recoveryLevel(level string) (qrcode.RecoveryLevel, error) {
+	switch strings.ToUpper(level) {
+	case "L":
+		return qrcode.Low, nil
+	case "M", "":
+		return qrcode.Medium, nil
+	case "Q":
+		return qrcode.High, nil
+	case "H":
+		return qrcode.Highest, nil
+	default:
+		return 0, fmt.Errorf("invalid error correction level %q", level)
+	}
+}
+
+// ParseColor parses a hex color such as "#1a2b3c", "1a2b3c" or "fff"
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.RGBA{R: b[0], G: b[1], B: b[2], A: 0xff}, nil
+}
+
+// HexColor formats a color as #rrggbb
+func HexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Default colors
+var (
+	Black = color.RGBA{A: 0xff}
+	White = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+)
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepEvery controls how often stale entries are purged, counted in calls to Allow
+const sweepEvery = 1024
+
+// LockoutConfig configures a Lockout
+type LockoutConfig struct {
+	MaxAttempts     int           // attempts allowed per key within Window
+	Window          time.Duration // fixed window for MaxAttempts
+	LockoutAfter    int           // consecutive failures before the key is locked out
+	LockoutDuration time.Duration // how long a locked out key is rejected
+}
+
+// Lockout rate limits attempts per key and locks a key out after repeated failures.
+// It is meant for guarding secrets such as link passwords against brute force.
+type Lockout struct {
+	cfg LockoutConfig
+
+	mu      sync.Mutex
+	entries map[string]*lockoutEntry
+	calls   int
+}
+
+type lockoutEntry struct {
+	windowStart time.Time
+	attempts    int
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// failuresExpired reports whether the consecutive failure count is old enough to forget
+func (e *lockoutEntry) failuresExpired(now time.Time, ttl time.Duration) bool {
+	return e.failures == 0 || now.Sub(e.lastFailure) >= ttl
+}
+
+// NewLockout creates a lockout tracker
+func NewLockout(cfg LockoutConfig) *Lockout {
+	return &Lockout{
+		cfg:     cfg,
+		entries: make(map[string]*lockoutEntry),
+	}
+}
+
+// Allow reports whether another attempt for key may be made now. When it may not,
+// the returned duration tells the caller when to retry.
+func (l *Lockout) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.calls++
+	if l.calls%sweepEvery == 0 {
+		l.sweep(now)
+	}
+
+	e, ok := l.entries[key]
+	if !ok {
+		e = &lockoutEntry{windowStart: now}
+		l.entries[key] = e
+	}
+
+	if now.Before(e.lockedUntil) {
+		return false, e.lockedUntil.Sub(now)
+	}
+
+	if now.Sub(e.windowStart) >= l.cfg.Window {
+		e.windowStart = now
+		e.attempts = 0
+	}
+	if e.failuresExpired(now, l.cfg.LockoutDuration) {
+		e.failures = 0
+	}
+	if e.attempts >= l.cfg.MaxAttempts {
+		return false, e.windowStart.Add(l.cfg.Window).Sub(now)
+	}
+
+	e.attempts++
+	return true, 0
+}
+
+// Failure records a failed attempt and locks the key out once the limit is reached
+func (l *Lockout) Failure(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	e.failures++
+	e.lastFailure = now
+	if e.failures >= l.cfg.LockoutAfter {
+		e.lockedUntil = now.Add(l.cfg.LockoutDuration)
+		e.failures = 0
+	}
+}
+
+// Success clears the failure history of key
+func (l *Lockout) Success(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
+
+// sweep drops entries that no longer limit anything
+func (l *Lockout) sweep(now time.Time) {
+	for key, e := range l.entries {
+		if now.After(e.lockedUntil) && now.Sub(e.windowStart) >= l.cfg.Window && e.failuresExpired(now, l.cfg.LockoutDuration) {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// fieldPattern matches the path of a JSON field. Array indices are not part of
+// paths, so "rules.destination_url" matches the field in every rule.
+type fieldPattern struct {
+	segments []string // lower-case keys, "*" matches any key
+	anywhere bool     // a single key that matches at any depth
+}
+
+func parseFieldPattern(pattern string) fieldPattern {
+	segments := strings.Split(strings.ToLower(pattern), ".")
+	return fieldPattern{segments: segments, anywhere: len(segments) == 1}
+}
+
+func (p fieldPattern) matches(path []string) bool {
+	if p.anywhere {
+		return len(path) > 0 && (p.segments[0] == "*" || p.segments[0] == path[len(path)-1])
+	}
+	if len(path) != len(p.segments) {
+		return false
+	}
+	for i, segment := range p.segments {
+		if segment != "*" && segment != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonFrame is an object or array the masker is inside of
+type jsonFrame struct {
+	object    bool
+	key       string // lower-case key of the current member of an object
+	count     int    // members written so far
+	expectKey bool   // the next token of an object is a key
+}
+
+// maskJSON re-encodes body token by token, replacing the values of masked
+// fields. Key order is kept. Invalid or truncated JSON is masked up to the
+// point where it stops being valid and the rest is dropped, so secrets in a
+// truncated body are never logged.
+func (r *Redactor) maskJSON(body []byte) string {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var (
+		out   bytes.Buffer
+		stack []jsonFrame
+		skip  int // depth inside a masked object or array
+	)
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			// io.EOF at the end of the body, anything else where it stops being valid JSON
+			return out.String()
+		}
+
+		delim, isDelim := tok.(json.Delim)
+		if skip > 0 {
+			if delim == '{' || delim == '[' {
+				skip++
+			} else if delim == '}' || delim == ']' {
+				skip--
+			}
+			continue
+		}
+
+		if delim == '}' || delim == ']' {
+			stack = stack[:len(stack)-1]
+			out.WriteByte(byte(delim))
+			continue
+		}
+
+		if n := len(stack); n > 0 {
+			frame := &stack[n-1]
+			if frame.object && frame.expectKey {
+				key, _ := tok.(string)
+				if frame.count > 0 {
+					out.WriteByte(',')
+				}
+				frame.count++
+				frame.key = strings.ToLower(key)
+				frame.expectKey = false
+				writeJSON(&out, key)
+				out.WriteByte(':')
+				continue
+			}
+			if frame.object {
+				frame.expectKey = true
+			} else {
+				if frame.count > 0 {
+					out.WriteByte(',')
+				}
+				frame.count++
+			}
+		}
+
+		if r.masked(stack) {
+			writeJSON(&out, r.mask)
+			if isDelim {
+				skip = 1
+			}
+			continue
+		}
+		if isDelim {
+			out.WriteByte(byte(delim))
+			stack = append(stack, jsonFrame{object: delim == '{', expectKey: delim == '{'})
+			continue
+		}
+		writeJSON(&out, tok)
+	}
+}
+
+// masked reports whether the value about to be written is masked. Its path
+// consists of the current keys of all enclosing objects.
+func (r *Redactor) masked(stack []jsonFrame) bool {
+	path := make([]string, 0, len(stack))
+	for _, frame := range stack {
+		if frame.object {
+			path = append(path, frame.key)
+		}
+	}
+	if len(path) == 0 {
+		return false
+	}
+	for _, field := range r.fields {
+		if field.matches(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJSON encodes a token without escaping HTML characters, so URLs stay readable
+func writeJSON(out *bytes.Buffer, v any) {
+	if number, ok := v.(json.Number); ok {
+		out.WriteString(number.String())
+		return
+	}
+
+	var encoded bytes.Buffer
+	enc := json.NewEncoder(&encoded)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	out.Write(bytes.TrimSuffix(encoded.Bytes(), []byte("\n")))
+}
@@ -0,0 +1,164 @@
+package redact
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DefaultMask replaces redacted values
+const DefaultMask = "[REDACTED]"
+
+// Config configures a Redactor
+type Config struct {
+	HeaderAllowlist []string // when set, only these headers are logged unmasked
+	HeaderDenylist  []string // headers whose values are masked
+	QueryParams     []string // query and form parameters whose values are masked
+	JSONFields      []string // JSON fields masked, "name" at any depth or a dotted path from the root with * wildcards
+	Mask            string   // replacement of masked values, DefaultMask if empty
+}
+
+// Redactor masks secrets in headers, URLs and bodies before they are logged
+type Redactor struct {
+	allow  map[string]bool
+	deny   map[string]bool
+	params map[string]bool
+	fields []fieldPattern
+	mask   string
+}
+
+// New creates a Redactor. Header and parameter names are matched case-insensitively.
+func New(cfg Config) *Redactor {
+	r := &Redactor{
+		allow:  canonicalHeaders(cfg.HeaderAllowlist),
+		deny:   canonicalHeaders(cfg.HeaderDenylist),
+		params: make(map[string]bool, len(cfg.QueryParams)),
+		mask:   cfg.Mask,
+	}
+	if r.mask == "" {
+		r.mask = DefaultMask
+	}
+	for _, param := range cfg.QueryParams {
+		r.params[strings.ToLower(param)] = true
+	}
+	for _, field := range cfg.JSONFields {
+		if field != "" {
+			r.fields = append(r.fields, parseFieldPattern(field))
+		}
+	}
+	return r
+}
+
+// Headers formats headers one per line in a stable order, masking the values of
+// denied headers and, when an allowlist is set, of every header not on it
+func (r *Redactor) Headers(headers http.Header) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := &bytes.Buffer{}
+	for _, name := range names {
+		masked := r.maskHeader(name)
+		for _, value := range headers[name] {
+			if masked {
+				value = r.mask
+			}
+			buf.WriteString(name)
+			buf.WriteString(": ")
+			buf.WriteString(value)
+			buf.WriteString("\n")
+		}
+	}
+	return buf.String()
+}
+
+func (r *Redactor) maskHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	if r.deny[name] {
+		return true
+	}
+	return len(r.allow) > 0 && !r.allow[name]
+}
+
+// Query returns the raw query with the values of masked parameters replaced.
+// Parameter order and the encoding of other values are kept.
+func (r *Redactor) Query(rawQuery string) string {
+	if rawQuery == "" || len(r.params) == 0 {
+		return rawQuery
+	}
+
+	parts := strings.Split(rawQuery, "&")
+	for i, part := range parts {
+		key, _, hasValue := strings.Cut(part, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if hasValue && r.params[strings.ToLower(name)] {
+			parts[i] = key + "=" + url.QueryEscape(r.mask)
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// Body returns body with masked JSON fields, or masked parameters for form
+// bodies. Bodies that look like JSON are masked as JSON whatever their content
+// type claims. Other bodies are returned unchanged.
+func (r *Redactor) Body(contentType string, body []byte) string {
+	mediaType := MediaType(contentType)
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+
+	switch {
+	case len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '['):
+		if len(r.fields) == 0 {
+			return string(body)
+		}
+		return r.maskJSON(body)
+	case mediaType == "application/x-www-form-urlencoded":
+		return r.Query(string(body))
+	}
+	return string(body)
+}
+
+// MediaType returns the lower-case media type of a Content-Type header without parameters
+func MediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, _, _ = strings.Cut(contentType, ";")
+	}
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// TypeAllowed reports whether the media type of contentType matches one of
+// allowed, which holds media types such as "application/json", prefixes ending
+// in "/" such as "text/", or "+json"-style structured syntax suffixes
+func TypeAllowed(contentType string, allowed []string) bool {
+	mediaType := MediaType(contentType)
+	if mediaType == "" {
+		return false
+	}
+	return slices.ContainsFunc(allowed, func(pattern string) bool {
+		pattern = strings.ToLower(pattern)
+		switch {
+		case strings.HasSuffix(pattern, "/"):
+			return strings.HasPrefix(mediaType, pattern)
+		case strings.HasPrefix(pattern, "+"):
+			return strings.HasSuffix(mediaType, pattern)
+		}
+		return mediaType == pattern
+	})
+}
+
+func canonicalHeaders(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[http.CanonicalHeaderKey(name)] = true
+	}
+	return set
+}
@@ -0,0 +1,80 @@
+package redirect
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/urlutil"
+)
+
+// ValidStatus reports whether status may be used for a short link redirect
+func ValidStatus(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// Permanent reports whether clients and caches may remember a redirect with status
+func Permanent(status int) bool {
+	return status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect
+}
+
+// CacheControl returns the Cache-Control header value for a redirect. Permanent
+// redirects of links that send every visitor to the same place may be cached
+// for maxAge; everything else must reach the gateway on every visit.
+func CacheControl(status int, cacheable bool, maxAge time.Duration) string {
+	if Permanent(status) && cacheable && maxAge > 0 {
+		return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+	}
+	return "private, no-store"
+}
+
+// Location builds the redirect target from destination and the query string of
+// the incoming request. The destination's own query parameters are kept in
+// their original order and encoding; incoming parameters are merged according
+// to query. Destinations that cannot be parsed are returned unchanged.
+func Location(destination string, incoming url.Values, query model.QueryMode, fragment model.FragmentMode) string {
+	forward := query != "" && query != model.QueryOff && len(incoming) > 0
+	dropFragment := fragment == model.FragmentVisitor
+	if !forward && !dropFragment {
+		return destination
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+
+	if forward {
+		u.RawQuery = mergeQuery(u.RawQuery, incoming, query)
+	}
+	if dropFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+	return u.String()
+}
+
+// mergeQuery merges incoming parameters into the raw destination query
+func mergeQuery(raw string, incoming url.Values, mode model.QueryMode) string {
+	existing, _ := url.ParseQuery(raw)
+
+	extra := url.Values{}
+	for key, values := range incoming {
+		_, conflict := existing[key]
+		switch {
+		case !conflict, mode == model.QueryAppend:
+			extra[key] = values
+		case mode == model.QueryOverride:
+			extra[key] = values
+			raw = urlutil.RemoveQueryKey(raw, key)
+		}
+	}
+
+	return urlutil.JoinQuery(raw, extra.Encode())
+}
@@ -1,9 +1,11 @@
 package server
 
 import (
+	"net/http"
+
+	"github.com/hohotang/shortlink-gateway/internal/accesslog"
 	"github.com/hohotang/shortlink-gateway/internal/handler"
 	"github.com/hohotang/shortlink-gateway/internal/middleware"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/hohotang/shortlink-gateway/docs" // swagger generated docs
@@ -15,6 +17,8 @@ type Router struct {
 	engine           *gin.Engine
 	middleware       middleware.Middleware
 	shortlinkHandler *handler.ShortlinkHandler
+	accessLog        *accesslog.Logger // nil when the access log is disabled
+	metrics          http.Handler      // serves /metrics, nil when Prometheus is disabled
 }
 
 func NewRouter(engine *gin.Engine, mw middleware.Middleware, shortlinkHandler *handler.ShortlinkHandler) *Router {
@@ -29,14 +33,37 @@ func NewRouter(engine *gin.Engine, mw middleware.Middleware, shortlinkHandler *h
 func (r *Router) InitRoute() {
 	// Metrics endpoint should be registered first and WITHOUT any middleware
 	// that might interfere with Prometheus scraping
-	r.engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	if r.metrics != nil {
+		r.engine.GET("/metrics", gin.WrapH(r.metrics))
+	}
 
 	// API routes with middleware
 	api := r.engine.Group("/")
-	api.Use(r.middleware.Otel(), r.middleware.LoggingMiddleware(), r.middleware.MetricsMiddleware(), r.middleware.RecoveryMiddleware())
+	api.Use(r.middleware.Otel())
+	if r.accessLog != nil {
+		api.Use(r.middleware.AccessLog(r.accessLog))
+	}
+	api.Use(r.middleware.LoggingMiddleware(), r.middleware.MetricsMiddleware(), r.middleware.RecoveryMiddleware())
 	{
-		api.POST("v1/shorten", r.shortlinkHandler.Shorten)
+		api.POST("v1/shorten", r.middleware.Idempotency(), r.shortlinkHandler.Shorten)
 		api.GET("v1/expand/:shortID", r.shortlinkHandler.Expand)
+		api.POST("v1/expand/:shortID", r.shortlinkHandler.Unlock)
+		api.GET("v1/links/:shortID/qr", r.shortlinkHandler.QRCode)
+		api.POST("v1/links/:shortID/sign", r.middleware.AdminAuth(), r.shortlinkHandler.Sign)
+
+		webhooks := api.Group("v1/webhooks", r.middleware.AdminAuth())
+		webhooks.POST("", r.shortlinkHandler.CreateWebhook)
+		webhooks.GET("", r.shortlinkHandler.ListWebhooks)
+		webhooks.GET(":id", r.shortlinkHandler.GetWebhook)
+		webhooks.DELETE(":id", r.shortlinkHandler.DeleteWebhook)
+		webhooks.POST(":id/ping", r.shortlinkHandler.PingWebhook)
+		webhooks.GET(":id/deliveries", r.shortlinkHandler.WebhookDeliveries)
+		webhooks.POST(":id/deliveries/:deliveryID/replay", r.shortlinkHandler.ReplayWebhookDelivery)
+
+		admin := api.Group("v1/admin", r.middleware.AdminAuth())
+		admin.GET("log-level", r.shortlinkHandler.GetLogLevel)
+		admin.PUT("log-level", r.shortlinkHandler.SetLogLevel)
+		admin.POST("debug-token", r.shortlinkHandler.MintDebugToken)
 	}
 
 	// Swagger documentation route
@@ -2,16 +2,34 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 
+	"github.com/hohotang/shortlink-gateway/internal/accesslog"
+	"github.com/hohotang/shortlink-gateway/internal/analytics"
+	"github.com/hohotang/shortlink-gateway/internal/blocklist"
+	"github.com/hohotang/shortlink-gateway/internal/cache"
+	"github.com/hohotang/shortlink-gateway/internal/chain"
 	"github.com/hohotang/shortlink-gateway/internal/config"
 	"github.com/hohotang/shortlink-gateway/internal/engine"
+	"github.com/hohotang/shortlink-gateway/internal/geoip"
 	"github.com/hohotang/shortlink-gateway/internal/handler"
 	"github.com/hohotang/shortlink-gateway/internal/middleware"
 	"github.com/hohotang/shortlink-gateway/internal/otel"
+	"github.com/hohotang/shortlink-gateway/internal/page"
+	"github.com/hohotang/shortlink-gateway/internal/qr"
+	"github.com/hohotang/shortlink-gateway/internal/ratelimit"
 	"github.com/hohotang/shortlink-gateway/internal/service"
+	"github.com/hohotang/shortlink-gateway/internal/signing"
+	"github.com/hohotang/shortlink-gateway/internal/useragent"
+	"github.com/hohotang/shortlink-gateway/internal/webhook"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
 	"github.com/gin-gonic/gin"
@@ -21,13 +39,23 @@ type Server struct {
 	router     *gin.Engine
 	config     *config.Config
 	httpServer *http.Server
-	urlService service.URLService
-	telemetry  *otel.Telemetry
+	// metricsServer serves /metrics on its own address, nil when it is served by router
+	metricsServer *http.Server
+	urlService    service.URLService
+	telemetry     *otel.Telemetry
+	logger        *zap.Logger
+	closers       []io.Closer
 }
 
 func New(cfg *config.Config, logger *zap.Logger, telemetry *otel.Telemetry) *Server {
 	// Create engine
 	engine := engine.NewEngine(cfg)
+	templates, err := page.Templates(cfg.Pages.TemplatesDir)
+	if err != nil {
+		logger.Error("Failed to load page templates, using the built-in pages", zap.Error(err))
+		templates, _ = page.Templates("")
+	}
+	engine.SetHTMLTemplate(templates)
 
 	// Create middleware
 	mw := middleware.NewMiddleware(cfg, logger, telemetry)
@@ -37,7 +65,7 @@ func New(cfg *config.Config, logger *zap.Logger, telemetry *otel.Telemetry) *Ser
 
 	// Choose between mock or real gRPC client based on configuration
 	if cfg.UseGrpc {
-		grpcClient, err := service.NewURLGrpcClient(cfg.GrpcServerAddr, cfg)
+		grpcClient, err := service.NewURLGrpcClient(cfg.GrpcServerAddr, cfg, telemetry)
 		if err != nil {
 			log.Printf("Failed to create gRPC client: %v, falling back to mock", err)
 			urlService = service.NewURLService()
@@ -48,24 +76,179 @@ func New(cfg *config.Config, logger *zap.Logger, telemetry *otel.Telemetry) *Ser
 		urlService = service.NewURLService() // Use default mock implementation
 	}
 
+	if cfg.LinkCache.Enabled {
+		urlService = service.NewCachedURLService(urlService, cfg.LinkCache.Size, cfg.LinkCache.TTL, telemetry.Metrics)
+	}
+
+	var closers []io.Closer
+
+	if err := qr.ValidateLevel(cfg.QR.DefaultLevel); err != nil {
+		logger.Error("Invalid qr.default_level, using M", zap.Error(err))
+		cfg.QR.DefaultLevel = "M"
+	}
+
 	// Create handlers
-	shortlinkHandler := handler.NewShortlinkHandler(urlService)
+	shortlinkHandler := handler.NewShortlinkHandler(cfg, urlService)
+	shortlinkHandler.Metrics = telemetry.Metrics
+	shortlinkHandler.Clicks = analytics.NewRecorder(cfg.ClickStats.Size, cfg.ClickStats.TTL, cfg.ClickStats.MaxVisitors, telemetry.Metrics)
+	shortlinkHandler.QRCache = cache.NewLRU[string, []byte](cfg.QR.CacheSize, cfg.QR.MaxAge)
+	shortlinkHandler.PasswordGuard = ratelimit.NewLockout(ratelimit.LockoutConfig{
+		MaxAttempts:     cfg.Password.MaxAttempts,
+		Window:          cfg.Password.AttemptWindow,
+		LockoutAfter:    cfg.Password.LockoutAfter,
+		LockoutDuration: cfg.Password.LockoutDuration,
+	})
+	shortlinkHandler.UnlockSecret = secretOrRandom(cfg.Password.CookieSecret, "password.cookie_secret", logger)
+
+	if cfg.Signing.Enabled {
+		signer, err := signing.NewSigner(cfg.Signing.Keys, cfg.Signing.ActiveKeyID)
+		if err != nil {
+			logger.Error("Invalid signing keys, signed links disabled", zap.Error(err))
+		} else {
+			shortlinkHandler.Signer = signer
+		}
+	}
+
+	shortDomains := cfg.Chaining.ShortDomains
+	if base, err := url.Parse(cfg.BaseURL); err == nil && base.Hostname() != "" {
+		shortDomains = append([]string{base.Hostname()}, shortDomains...)
+	}
+	shortlinkHandler.Chain = chain.NewResolver(chain.Config{
+		OwnDomains:  shortDomains,
+		Shorteners:  cfg.Chaining.KnownShorteners,
+		MaxHops:     cfg.Chaining.MaxHops,
+		HTTPTimeout: cfg.Chaining.ResolveTimeout,
+	}, urlService)
+
+	if cfg.Blocklist.Enabled {
+		list, err := blocklist.New(blocklist.Config{
+			DomainsPath:      cfg.Blocklist.DomainsPath,
+			SuffixesPath:     cfg.Blocklist.SuffixesPath,
+			PatternsPath:     cfg.Blocklist.PatternsPath,
+			ReloadInterval:   cfg.Blocklist.ReloadInterval,
+			ProtectedDomains: cfg.Blocklist.ProtectedDomains,
+			BlockMixedScript: cfg.Blocklist.BlockMixedScript,
+		}, telemetry.Metrics, logger)
+		if err != nil {
+			logger.Error("Failed to load blocklist, destination checks disabled", zap.Error(err))
+		} else {
+			shortlinkHandler.Blocklist = list
+			closers = append(closers, list)
+		}
+	}
+
+	if cfg.Webhooks.Enabled {
+		dispatcher, err := webhook.New(webhook.Config{
+			SubscriptionsPath: cfg.Webhooks.SubscriptionsPath,
+			DeadLetterPath:    cfg.Webhooks.DeadLetterPath,
+			Workers:           cfg.Webhooks.Workers,
+			QueueSize:         cfg.Webhooks.QueueSize,
+			MaxAttempts:       cfg.Webhooks.MaxAttempts,
+			InitialBackoff:    cfg.Webhooks.InitialBackoff,
+			MaxBackoff:        cfg.Webhooks.MaxBackoff,
+			Timeout:           cfg.Webhooks.Timeout,
+			LogSize:           cfg.Webhooks.LogSize,
+			MaxScheduled:      cfg.Webhooks.MaxScheduled,
+		}, telemetry.Metrics, logger)
+		if err != nil {
+			logger.Error("Failed to load webhook subscriptions, webhooks disabled", zap.Error(err))
+		} else {
+			shortlinkHandler.Webhooks = dispatcher
+			closers = append(closers, dispatcher)
+		}
+	}
+
+	if cfg.Classifier.Enabled {
+		classifier, err := useragent.NewClassifier(cfg.Classifier.RulesPath, cfg.Classifier.ReloadInterval, logger)
+		if err != nil {
+			logger.Error("Failed to load user agent rules, click classification disabled", zap.Error(err))
+		} else {
+			shortlinkHandler.Classifier = classifier
+			closers = append(closers, classifier)
+		}
+	}
+
+	if cfg.GeoIP.Enabled {
+		resolver, err := geoip.NewResolver(cfg.GeoIP.DatabasePath, cfg.GeoIP.ASNDatabasePath, cfg.GeoIP.ReloadInterval, logger)
+		if err != nil {
+			logger.Error("Failed to open geoip database, geolocation disabled", zap.Error(err))
+		} else {
+			shortlinkHandler.GeoIP = resolver
+			closers = append(closers, resolver)
+		}
+	}
 
 	// Create and initialize router
 	router := NewRouter(engine, mw, shortlinkHandler)
+	if cfg.AccessLog.Enabled {
+		format, err := accesslog.ParseFormat(cfg.AccessLog.Format)
+		if err != nil {
+			logger.Error("Failed to set up the access log, it is disabled", zap.Error(err))
+		} else {
+			router.accessLog = accesslog.New(accesslog.Config{
+				Format:     format,
+				Path:       cfg.AccessLog.Path,
+				MaxSizeMB:  cfg.AccessLog.MaxSizeMB,
+				MaxBackups: cfg.AccessLog.MaxBackups,
+				MaxAgeDays: cfg.AccessLog.MaxAgeDays,
+				Compress:   cfg.AccessLog.Compress,
+			})
+			closers = append(closers, router.accessLog)
+		}
+	}
+	var metricsServer *http.Server
+	if telemetry.Registry != nil {
+		// OpenMetrics is needed for exemplars, plain scrapes still get the text format
+		metrics := promhttp.HandlerFor(telemetry.Registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+		if cfg.Metrics.Listen == "" {
+			router.metrics = metrics
+		} else {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics)
+			metricsServer = &http.Server{Addr: cfg.Metrics.Listen, Handler: mux}
+		}
+	}
 	router.InitRoute()
 
 	return &Server{
-		router:     engine,
-		config:     cfg,
-		urlService: urlService,
-		telemetry:  telemetry,
+		router:        engine,
+		config:        cfg,
+		metricsServer: metricsServer,
+		urlService:    urlService,
+		telemetry:     telemetry,
+		logger:        logger,
+		closers:       closers,
 	}
 }
 
+// secretOrRandom returns the configured secret, or a random one when it is empty.
+// A random secret only works for a single instance and does not survive restarts.
+func secretOrRandom(secret, key string, logger *zap.Logger) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+
+	logger.Warn("No secret configured, using a random one for this process", zap.String("config", key))
+	random := make([]byte, 32)
+	_, _ = rand.Read(random)
+	return random
+}
+
 func (s *Server) Run() error {
 	addr := fmt.Sprintf(":%d", s.config.Port)
 
+	if s.metricsServer != nil {
+		listener, err := net.Listen("tcp", s.metricsServer.Addr)
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := s.metricsServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.logger.Error("Metrics server failed", zap.String("addr", s.metricsServer.Addr), zap.Error(err))
+			}
+		}()
+	}
+
 	s.httpServer = &http.Server{
 		Addr:    addr,
 		Handler: s.router,
@@ -81,6 +264,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	if s.httpServer != nil {
 		err = s.httpServer.Shutdown(ctx)
 	}
+	if s.metricsServer != nil {
+		if shutdownErr := s.metricsServer.Shutdown(ctx); shutdownErr != nil && err == nil {
+			err = shutdownErr
+		}
+	}
 
 	// Then close the URL service
 	if s.urlService != nil {
@@ -90,5 +278,12 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		}
 	}
 
+	// Finally stop background components such as file watchers
+	for _, closer := range s.closers {
+		if closeErr := closer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
 	return err
 }
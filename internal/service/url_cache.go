@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/hohotang/shortlink-gateway/internal/cache"
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/otel"
+)
+
+// CachedURLService caches expanded links in memory so that hot links and their
+// targeting rules are served without a round trip to the core
+type CachedURLService struct {
+	next    URLService
+	links   *cache.LRU[string, *model.Link]
+	metrics *otel.Metrics
+}
+
+// NewCachedURLService wraps next with an LRU cache of at most size links, each kept for ttl.
+// Hits and misses are counted in metrics, which may be nil.
+func NewCachedURLService(next URLService, size int, ttl time.Duration, metrics *otel.Metrics) URLService {
+	return &CachedURLService{
+		next:    next,
+		links:   cache.NewLRU[string, *model.Link](size, ttl),
+		metrics: metrics,
+	}
+}
+
+// ShortenURL creates a short URL and caches the new link
+func (s *CachedURLService) ShortenURL(ctx context.Context, link *model.Link) (string, error) {
+	shortID, err := s.next.ShortenURL(ctx, link)
+	if err != nil {
+		return "", err
+	}
+
+	stored := *link
+	stored.ShortID = shortID
+	stored.PasswordProtected = link.Password != ""
+	stored.Password = ""
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
+	}
+	s.links.Set(shortID, &stored)
+
+	return shortID, nil
+}
+
+// ExpandURL returns the cached link or resolves it through the wrapped service.
+// Returned links are shared between requests and must not be modified.
+func (s *CachedURLService) ExpandURL(ctx context.Context, shortID string) (*model.Link, error) {
+	link, ok := s.links.Get(shortID)
+	s.metrics.CacheLookup(ctx, "link", ok)
+	if ok {
+		return link, nil
+	}
+
+	link, err := s.next.ExpandURL(ctx, shortID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.links.Set(shortID, link)
+	return link, nil
+}
+
+// VerifyPassword is never cached so that every attempt reaches the core
+func (s *CachedURLService) VerifyPassword(ctx context.Context, shortID, password string) (bool, error) {
+	return s.next.VerifyPassword(ctx, shortID, password)
+}
+
+// FindURL is never cached, the wrapped service owns the index of links
+func (s *CachedURLService) FindURL(ctx context.Context, owner string, link *model.Link) (string, error) {
+	return s.next.FindURL(ctx, owner, link)
+}
+
+// Close closes the wrapped service
+func (s *CachedURLService) Close() error {
+	return s.next.Close()
+}
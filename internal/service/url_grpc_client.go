@@ -2,13 +2,22 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/hohotang/shortlink-gateway/internal/config"
+	"github.com/hohotang/shortlink-gateway/internal/geoip"
+	"github.com/hohotang/shortlink-gateway/internal/logger"
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/otel"
 	pb "github.com/hohotang/shortlink-gateway/proto"
 
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 // URLGrpcClient implements the URLService interface using gRPC
@@ -18,11 +27,20 @@ type URLGrpcClient struct {
 	cfg    *config.Config
 }
 
-// NewURLGrpcClient creates a new URL service gRPC client
-func NewURLGrpcClient(serverAddr string, cfg *config.Config) (*URLGrpcClient, error) {
+// NewURLGrpcClient creates a new URL service gRPC client. Calls are traced and
+// measured with the providers of telemetry.
+func NewURLGrpcClient(serverAddr string, cfg *config.Config, telemetry *otel.Telemetry) (*URLGrpcClient, error) {
+	interceptors := []grpc.UnaryClientInterceptor{observeCallInterceptor(telemetry.Metrics)}
+	if cfg.GeoIP.ForwardMetadata {
+		interceptors = append(interceptors, forwardLocationInterceptor)
+	}
 	options := []grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler(
+			otelgrpc.WithTracerProvider(telemetry.TracerProvider),
+			otelgrpc.WithMeterProvider(telemetry.MeterProvider),
+		)),
+		grpc.WithChainUnaryInterceptor(interceptors...),
 	}
 	// Create connection to gRPC service
 	cc, err := grpc.NewClient(serverAddr, options...)
@@ -41,15 +59,13 @@ func NewURLGrpcClient(serverAddr string, cfg *config.Config) (*URLGrpcClient, er
 }
 
 // ShortenURL implements URLService.ShortenURL using gRPC
-func (s *URLGrpcClient) ShortenURL(ctx context.Context, originalURL string) (string, error) {
+func (s *URLGrpcClient) ShortenURL(ctx context.Context, link *model.Link) (string, error) {
 	// Add timeout from config
 	ctx, cancel := context.WithTimeout(ctx, s.cfg.GrpcTimeout)
 	defer cancel()
 
 	// Call gRPC method
-	resp, err := s.client.ShortenURL(ctx, &pb.ShortenURLRequest{
-		OriginalUrl: originalURL,
-	})
+	resp, err := s.client.ShortenURL(ctx, toProtoShortenRequest(link))
 	if err != nil {
 		return "", err
 	}
@@ -58,7 +74,7 @@ func (s *URLGrpcClient) ShortenURL(ctx context.Context, originalURL string) (str
 }
 
 // ExpandURL implements URLService.ExpandURL using gRPC
-func (s *URLGrpcClient) ExpandURL(ctx context.Context, shortID string) (string, error) {
+func (s *URLGrpcClient) ExpandURL(ctx context.Context, shortID string) (*model.Link, error) {
 	// Add timeout from config
 	ctx, cancel := context.WithTimeout(ctx, s.cfg.GrpcTimeout)
 	defer cancel()
@@ -67,11 +83,275 @@ func (s *URLGrpcClient) ExpandURL(ctx context.Context, shortID string) (string,
 	resp, err := s.client.ExpandURL(ctx, &pb.ExpandURLRequest{
 		ShortId: shortID,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	var createdAt time.Time
+	if resp.CreatedAt != 0 {
+		createdAt = time.Unix(resp.CreatedAt, 0)
+	}
+
+	return &model.Link{
+		ShortID:     shortID,
+		OriginalURL: resp.OriginalUrl,
+		Rules:       fromProtoRules(resp.Rules),
+		Variants:    fromProtoVariants(resp.Variants),
+		Stickiness:  fromProtoStickiness(resp.Stickiness),
+
+		PasswordProtected: resp.PasswordProtected,
+		SignatureRequired: resp.SignatureRequired,
+		Interstitial:      resp.Interstitial,
+		CreatedAt:         createdAt,
+		Redirect:          fromProtoRedirect(resp.Redirect),
+	}, nil
+}
+
+// VerifyPassword implements URLService.VerifyPassword using gRPC
+func (s *URLGrpcClient) VerifyPassword(ctx context.Context, shortID, password string) (bool, error) {
+	// Add timeout from config
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.GrpcTimeout)
+	defer cancel()
+
+	resp, err := s.client.VerifyPassword(ctx, &pb.VerifyPasswordRequest{
+		ShortId:  shortID,
+		Password: password,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return resp.Valid, nil
+}
+
+// FindURL implements URLService.FindURL using gRPC
+func (s *URLGrpcClient) FindURL(ctx context.Context, owner string, link *model.Link) (string, error) {
+	// Add timeout from config
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.GrpcTimeout)
+	defer cancel()
+
+	resp, err := s.client.FindURL(ctx, &pb.FindURLRequest{
+		Owner:         owner,
+		NormalizedUrl: NormalizedURL(link.OriginalURL),
+		Link:          toProtoShortenRequest(link),
+	})
 	if err != nil {
 		return "", err
 	}
 
-	return resp.OriginalUrl, nil
+	return resp.ShortId, nil
+}
+
+func toProtoShortenRequest(link *model.Link) *pb.ShortenURLRequest {
+	return &pb.ShortenURLRequest{
+		OriginalUrl: link.OriginalURL,
+		Rules:       toProtoRules(link.Rules),
+		Variants:    toProtoVariants(link.Variants),
+		Stickiness:  toProtoStickiness(link.Stickiness),
+		Password:    link.Password,
+
+		SignatureRequired: link.SignatureRequired,
+		Interstitial:      link.Interstitial,
+		Redirect:          toProtoRedirect(link.Redirect),
+
+		Owner:         link.Owner,
+		NormalizedUrl: NormalizedURL(link.OriginalURL),
+	}
+}
+
+func toProtoRules(rules []model.RedirectRule) []*pb.RedirectRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	out := make([]*pb.RedirectRule, 0, len(rules))
+	for _, rule := range rules {
+		r := &pb.RedirectRule{
+			Countries:      rule.Countries,
+			DeviceOs:       rule.DeviceOS,
+			Languages:      rule.Languages,
+			DestinationUrl: rule.DestinationURL,
+		}
+		if rule.NotBefore != nil {
+			r.NotBefore = rule.NotBefore.Unix()
+		}
+		if rule.NotAfter != nil {
+			r.NotAfter = rule.NotAfter.Unix()
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func fromProtoRules(rules []*pb.RedirectRule) []model.RedirectRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	out := make([]model.RedirectRule, 0, len(rules))
+	for _, r := range rules {
+		rule := model.RedirectRule{
+			Countries:      r.Countries,
+			DeviceOS:       r.DeviceOs,
+			Languages:      r.Languages,
+			DestinationURL: r.DestinationUrl,
+		}
+		if r.NotBefore != 0 {
+			t := time.Unix(r.NotBefore, 0)
+			rule.NotBefore = &t
+		}
+		if r.NotAfter != 0 {
+			t := time.Unix(r.NotAfter, 0)
+			rule.NotAfter = &t
+		}
+		out = append(out, rule)
+	}
+	return out
+}
+
+func toProtoVariants(variants []model.Variant) []*pb.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	out := make([]*pb.Variant, 0, len(variants))
+	for _, v := range variants {
+		out = append(out, &pb.Variant{
+			Name:           v.Name,
+			DestinationUrl: v.DestinationURL,
+			Weight:         v.Weight,
+		})
+	}
+	return out
+}
+
+func fromProtoVariants(variants []*pb.Variant) []model.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	out := make([]model.Variant, 0, len(variants))
+	for _, v := range variants {
+		out = append(out, model.Variant{
+			Name:           v.Name,
+			DestinationURL: v.DestinationUrl,
+			Weight:         v.Weight,
+		})
+	}
+	return out
+}
+
+func toProtoStickiness(s model.Stickiness) pb.Stickiness {
+	switch s {
+	case model.StickinessCookie:
+		return pb.Stickiness_STICKINESS_COOKIE
+	case model.StickinessHash:
+		return pb.Stickiness_STICKINESS_HASH
+	default:
+		return pb.Stickiness_STICKINESS_NONE
+	}
+}
+
+func fromProtoStickiness(s pb.Stickiness) model.Stickiness {
+	switch s {
+	case pb.Stickiness_STICKINESS_COOKIE:
+		return model.StickinessCookie
+	case pb.Stickiness_STICKINESS_HASH:
+		return model.StickinessHash
+	default:
+		return model.StickinessNone
+	}
+}
+
+func toProtoRedirect(r model.RedirectOptions) *pb.RedirectOptions {
+	out := &pb.RedirectOptions{Status: int32(r.Status)}
+
+	switch r.Query {
+	case model.QueryOff:
+		out.QueryMode = pb.QueryMode_QUERY_MODE_OFF
+	case model.QueryKeep:
+		out.QueryMode = pb.QueryMode_QUERY_MODE_KEEP
+	case model.QueryOverride:
+		out.QueryMode = pb.QueryMode_QUERY_MODE_OVERRIDE
+	case model.QueryAppend:
+		out.QueryMode = pb.QueryMode_QUERY_MODE_APPEND
+	}
+
+	switch r.Fragment {
+	case model.FragmentDestination:
+		out.FragmentMode = pb.FragmentMode_FRAGMENT_MODE_DESTINATION
+	case model.FragmentVisitor:
+		out.FragmentMode = pb.FragmentMode_FRAGMENT_MODE_VISITOR
+	}
+
+	return out
+}
+
+func fromProtoRedirect(r *pb.RedirectOptions) model.RedirectOptions {
+	var out model.RedirectOptions
+	if r == nil {
+		return out
+	}
+	out.Status = int(r.Status)
+
+	switch r.QueryMode {
+	case pb.QueryMode_QUERY_MODE_OFF:
+		out.Query = model.QueryOff
+	case pb.QueryMode_QUERY_MODE_KEEP:
+		out.Query = model.QueryKeep
+	case pb.QueryMode_QUERY_MODE_OVERRIDE:
+		out.Query = model.QueryOverride
+	case pb.QueryMode_QUERY_MODE_APPEND:
+		out.Query = model.QueryAppend
+	}
+
+	switch r.FragmentMode {
+	case pb.FragmentMode_FRAGMENT_MODE_DESTINATION:
+		out.Fragment = model.FragmentDestination
+	case pb.FragmentMode_FRAGMENT_MODE_VISITOR:
+		out.Fragment = model.FragmentVisitor
+	}
+
+	return out
+}
+
+// observeCallInterceptor records the latency and status code of every call to
+// the core, and logs it with the request-scoped logger so that its lines carry
+// the trace and request IDs of the HTTP request. Codes that answer the request,
+// such as NotFound, are logged at debug level.
+func observeCallInterceptor(metrics *otel.Metrics) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		latency := time.Since(start)
+
+		code := status.Code(err)
+		metrics.CoreCall(ctx, method, code.String(), latency)
+
+		fields := []zap.Field{
+			zap.String("grpc_method", method),
+			zap.String("grpc_code", code.String()),
+			zap.Duration("latency", latency),
+		}
+		switch code {
+		case codes.OK, codes.NotFound, codes.InvalidArgument, codes.AlreadyExists, codes.PermissionDenied, codes.Unauthenticated, codes.FailedPrecondition:
+			logger.FromContext(ctx).Debug("gRPC call", fields...)
+		default:
+			logger.FromContext(ctx).Warn("gRPC call failed", append(fields, zap.Error(err))...)
+		}
+		return err
+	}
+}
+
+// IsNotFound reports whether err means the short link does not exist
+func IsNotFound(err error) bool {
+	return status.Code(err) == codes.NotFound
+}
+
+// forwardLocationInterceptor forwards the client location resolved by the gateway to the core as metadata
+func forwardLocationInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if location, ok := geoip.FromContext(ctx); ok {
+		for key, value := range location.Metadata() {
+			ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+		}
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
 }
 
 // Close closes the gRPC connection
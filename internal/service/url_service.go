@@ -3,12 +3,25 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hohotang/shortlink-gateway/internal/model"
+	"github.com/hohotang/shortlink-gateway/internal/urlutil"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // URLService provides URL shortening functionality
 type URLService interface {
-	ShortenURL(ctx context.Context, originalURL string) (string, error)
-	ExpandURL(ctx context.Context, shortID string) (string, error)
+	ShortenURL(ctx context.Context, link *model.Link) (string, error)
+	ExpandURL(ctx context.Context, shortID string) (*model.Link, error)
+	VerifyPassword(ctx context.Context, shortID, password string) (bool, error)
+	// FindURL returns the ID of the oldest link of owner with the same normalized
+	// URL and settings as link, or "" if there is none
+	FindURL(ctx context.Context, owner string, link *model.Link) (string, error)
 	Close() error // Add Close method for cleanup
 }
 
@@ -20,7 +33,7 @@ type URLServiceImpl struct {
 // NewURLService creates a new URL service
 func NewURLService() URLService {
 	return &URLServiceImpl{
-		client: &MockURLService{}, // Default to mock implementation
+		client: NewMockURLService(), // Default to mock implementation
 	}
 }
 
@@ -31,16 +44,26 @@ func NewURLServiceWithClient(client URLService) URLService {
 	}
 }
 
-// ShortenURL creates a short URL from the original URL
-func (s *URLServiceImpl) ShortenURL(ctx context.Context, originalURL string) (string, error) {
-	return s.client.ShortenURL(ctx, originalURL)
+// ShortenURL creates a short URL for the link
+func (s *URLServiceImpl) ShortenURL(ctx context.Context, link *model.Link) (string, error) {
+	return s.client.ShortenURL(ctx, link)
 }
 
-// ExpandURL resolves a short URL to its original URL
-func (s *URLServiceImpl) ExpandURL(ctx context.Context, shortID string) (string, error) {
+// ExpandURL resolves a short URL to its link
+func (s *URLServiceImpl) ExpandURL(ctx context.Context, shortID string) (*model.Link, error) {
 	return s.client.ExpandURL(ctx, shortID)
 }
 
+// VerifyPassword checks a password attempt for a protected link
+func (s *URLServiceImpl) VerifyPassword(ctx context.Context, shortID, password string) (bool, error) {
+	return s.client.VerifyPassword(ctx, shortID, password)
+}
+
+// FindURL looks up an existing link to reuse
+func (s *URLServiceImpl) FindURL(ctx context.Context, owner string, link *model.Link) (string, error) {
+	return s.client.FindURL(ctx, owner, link)
+}
+
 // Close closes any resources held by the service
 func (s *URLServiceImpl) Close() error {
 	if closer, ok := s.client.(interface{ Close() error }); ok {
@@ -49,30 +72,155 @@ func (s *URLServiceImpl) Close() error {
 	return nil
 }
 
-// MockURLService provides a local implementation for testing/development
-type MockURLService struct{}
+// MockURLService provides a local in-memory implementation for testing/development
+type MockURLService struct {
+	mu        sync.RWMutex
+	links     map[string]*model.Link
+	passwords map[string][]byte   // bcrypt hashes, like the core stores them
+	owned     map[string][]string // short IDs by owner and normalized URL, oldest first
+	seq       int64
+}
+
+// NewMockURLService creates an empty in-memory URL service
+func NewMockURLService() *MockURLService {
+	return &MockURLService{
+		links:     make(map[string]*model.Link),
+		passwords: make(map[string][]byte),
+		owned:     make(map[string][]string),
+	}
+}
 
 // ShortenURL creates a short URL from the original URL
-func (s *MockURLService) ShortenURL(ctx context.Context, originalURL string) (string, error) {
+func (s *MockURLService) ShortenURL(ctx context.Context, link *model.Link) (string, error) {
 	// This is a mock implementation for testing
-	if originalURL == "" {
+	if link == nil || link.OriginalURL == "" {
 		return "", errors.New("original URL cannot be empty")
 	}
 
-	return "abc123", nil
+	var hash []byte
+	if link.Password != "" {
+		var err error
+		if hash, err = bcrypt.GenerateFromPassword([]byte(link.Password), bcrypt.DefaultCost); err != nil {
+			return "", err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	shortID := "abc" + strconv.FormatInt(122+s.seq, 10) // abc123, abc124, ...
+
+	stored := *link
+	stored.ShortID = shortID
+	stored.Password = ""
+	stored.PasswordProtected = hash != nil
+	stored.CreatedAt = time.Now()
+	s.links[shortID] = &stored
+	if hash != nil {
+		s.passwords[shortID] = hash
+	}
+	if link.Owner != "" {
+		key := ownedKey(link.Owner, link.OriginalURL)
+		s.owned[key] = append(s.owned[key], shortID)
+	}
+
+	return shortID, nil
 }
 
 // ExpandURL resolves a short URL to its original URL
-func (s *MockURLService) ExpandURL(ctx context.Context, shortID string) (string, error) {
+func (s *MockURLService) ExpandURL(ctx context.Context, shortID string) (*model.Link, error) {
 	// This is a mock implementation for testing
 	if shortID == "" {
-		return "", errors.New("short ID cannot be empty")
+		return nil, errors.New("short ID cannot be empty")
 	}
 
-	return "https://example.com/original-url", nil
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if link, ok := s.links[shortID]; ok {
+		stored := *link
+		return &stored, nil
+	}
+
+	return &model.Link{ShortID: shortID, OriginalURL: "https://example.com/original-url"}, nil
+}
+
+// VerifyPassword checks a password attempt against the stored hash
+func (s *MockURLService) VerifyPassword(ctx context.Context, shortID, password string) (bool, error) {
+	s.mu.RLock()
+	hash, ok := s.passwords[shortID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return false, nil
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil, nil
+}
+
+// FindURL returns the oldest link of owner that matches link
+func (s *MockURLService) FindURL(ctx context.Context, owner string, link *model.Link) (string, error) {
+	if owner == "" || link == nil {
+		return "", nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, shortID := range s.owned[ownedKey(owner, link.OriginalURL)] {
+		if sameSettings(s.links[shortID], link) {
+			return shortID, nil
+		}
+	}
+	return "", nil
 }
 
 // Close is a no-op for the mock service
 func (s *MockURLService) Close() error {
 	return nil
 }
+
+// NormalizedURL returns raw in the canonical form used to find duplicate links,
+// the same form the blocklist and chaining checks validate. URLs that cannot be
+// normalized are returned unchanged.
+func NormalizedURL(raw string) string {
+	u, err := urlutil.Normalize(raw)
+	if err != nil {
+		return raw
+	}
+	return u.String()
+}
+
+func ownedKey(owner, raw string) string {
+	return owner + "\n" + NormalizedURL(raw)
+}
+
+// sameSettings reports whether the stored link behaves like a new link created
+// from link. Password protected links never match because only a hash is stored.
+func sameSettings(stored, link *model.Link) bool {
+	if stored.PasswordProtected || link.Password != "" {
+		return false
+	}
+	return slices.EqualFunc(stored.Rules, link.Rules, sameRule) &&
+		slices.Equal(stored.Variants, link.Variants) &&
+		stored.Stickiness == link.Stickiness &&
+		stored.SignatureRequired == link.SignatureRequired &&
+		stored.Interstitial == link.Interstitial &&
+		stored.Redirect == link.Redirect
+}
+
+func sameRule(a, b model.RedirectRule) bool {
+	return a.DestinationURL == b.DestinationURL &&
+		slices.Equal(a.Countries, b.Countries) &&
+		slices.Equal(a.DeviceOS, b.DeviceOS) &&
+		slices.Equal(a.Languages, b.Languages) &&
+		sameTime(a.NotBefore, b.NotBefore) &&
+		sameTime(a.NotAfter, b.NotAfter)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
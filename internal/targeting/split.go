@@ -0,0 +1,51 @@
+package targeting
+
+import (
+	"hash/fnv"
+	"math"
+
+	"github.com/hohotang/shortlink-gateway/internal/model"
+)
+
+// ChooseVariant assigns a visitor to one of the weighted variants and returns its index,
+// or -1 if no variant has a positive weight.
+//
+// It uses weighted rendezvous (highest random weight) hashing: every variant draws a
+// pseudo-random score from the visitor key and its own name, scaled by its weight, and
+// the highest score wins. The same key therefore always maps to the same variant, and
+// changing one variant's weight only moves the visitors that must move to reach the
+// new split, rather than reshuffling everyone as a cumulative-weight bucket would.
+func ChooseVariant(variants []model.Variant, visitorKey string) int {
+	best := -1
+	bestScore := math.Inf(-1)
+
+	for i, v := range variants {
+		if v.Weight == 0 {
+			continue
+		}
+		score := -float64(v.Weight) / math.Log(unitHash(visitorKey, v.Name))
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	return best
+}
+
+// unitHash maps (key, name) to a uniformly distributed float in the open interval (0, 1)
+func unitHash(key, name string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(name))
+
+	// splitmix64 finalizer to spread FNV's weak low bits
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return (float64(x>>11) + 0.5) / (1 << 53)
+}
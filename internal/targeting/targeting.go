@@ -0,0 +1,148 @@
+package targeting
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hohotang/shortlink-gateway/internal/model"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// NoMatch is the rule index reported when the default destination is used
+const NoMatch = -1
+
+// Visitor holds the request properties that redirect rules can match on
+type Visitor struct {
+	Country   string   // ISO 3166-1 alpha-2 code, empty if unknown
+	OS        string   // operating system name as reported by the user agent classifier
+	Languages []string // primary language subtags in order of preference
+	Time      time.Time
+	Key       string // stable visitor identity used for sticky split assignment
+}
+
+// Decision is the outcome of evaluating a link's rules for a visitor
+type Decision struct {
+	DestinationURL string
+	RuleIndex      int    // index of the matching rule, or NoMatch
+	Variant        string // name of the chosen split variant, empty if none
+}
+
+// Attributes returns the decision as OpenTelemetry attributes for spans
+func (d Decision) Attributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.Bool("redirect.rule_matched", d.RuleIndex != NoMatch),
+		attribute.Int("redirect.rule_index", d.RuleIndex),
+	}
+	if d.Variant != "" {
+		attrs = append(attrs, attribute.String("redirect.variant", d.Variant))
+	}
+	return attrs
+}
+
+// Evaluate picks the destination for a visitor: the first rule whose conditions all
+// match wins, then a weighted split variant if the link has any, and finally the
+// link's original URL
+func Evaluate(link *model.Link, visitor Visitor) Decision {
+	for i := range link.Rules {
+		if matches(&link.Rules[i], visitor) {
+			return Decision{DestinationURL: link.Rules[i].DestinationURL, RuleIndex: i}
+		}
+	}
+
+	if i := ChooseVariant(link.Variants, visitor.Key); i >= 0 {
+		v := link.Variants[i]
+		return Decision{DestinationURL: v.DestinationURL, RuleIndex: NoMatch, Variant: v.Name}
+	}
+
+	return Decision{DestinationURL: link.OriginalURL, RuleIndex: NoMatch}
+}
+
+// matches reports whether every non-empty condition of the rule holds for the visitor
+func matches(rule *model.RedirectRule, visitor Visitor) bool {
+	if rule.DestinationURL == "" {
+		return false
+	}
+	if len(rule.Countries) > 0 && !containsFold(rule.Countries, visitor.Country) {
+		return false
+	}
+	if len(rule.DeviceOS) > 0 && !containsFold(rule.DeviceOS, visitor.OS) {
+		return false
+	}
+	if len(rule.Languages) > 0 && !matchesLanguage(rule.Languages, visitor.Languages) {
+		return false
+	}
+	if rule.NotBefore != nil && visitor.Time.Before(*rule.NotBefore) {
+		return false
+	}
+	if rule.NotAfter != nil && !visitor.Time.Before(*rule.NotAfter) {
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, target string) bool {
+	if target == "" {
+		return false
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesLanguage reports whether any preferred language of the visitor is listed by the rule
+func matchesLanguage(ruleLanguages, visitorLanguages []string) bool {
+	for _, lang := range visitorLanguages {
+		if containsFold(ruleLanguages, lang) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAcceptLanguage returns the primary language subtags of an Accept-Language header,
+// ordered by preference and without duplicates. Languages with q=0 are dropped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+
+	var langs []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+		primary = strings.ToLower(primary)
+		if primary == "" || primary == "*" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		langs = append(langs, weighted{lang: primary, q: q})
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	seen := make(map[string]bool, len(langs))
+	out := make([]string, 0, len(langs))
+	for _, l := range langs {
+		if !seen[l.lang] {
+			seen[l.lang] = true
+			out = append(out, l.lang)
+		}
+	}
+	return out
+}
@@ -0,0 +1,161 @@
+package urlutil
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrInvalidURL is returned for URLs that are not absolute http or https URLs
+var ErrInvalidURL = errors.New("not an absolute http or https URL")
+
+// Normalize parses raw as an absolute http or https URL and returns it in a
+// canonical form: lower-case scheme, host converted to lower-case ASCII
+// (punycode for internationalised names) without a trailing dot, default
+// ports removed and an empty path replaced by "/". Query and fragment are kept
+// as they are.
+func Normalize(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, ErrInvalidURL
+	}
+
+	host, err := NormalizeHost(u.Hostname())
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	u.Host = host
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u, nil
+}
+
+// NormalizeHost returns host in lower-case ASCII form without a trailing dot.
+// IP addresses and plain ASCII names that IDNA rejects (e.g. with underscores)
+// are returned unchanged apart from the case.
+func NormalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		if isASCII(host) {
+			return host, nil
+		}
+		return "", err
+	}
+	return ascii, nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// UnicodeHost returns the Unicode form of a normalised host, for display and
+// lookalike detection. Hosts that fail to convert are returned unchanged.
+func UnicodeHost(host string) string {
+	unicode, err := idna.Display.ToUnicode(host)
+	if err != nil {
+		return host
+	}
+	return unicode
+}
+
+// MatchesDomain reports whether host equals domain or is a subdomain of it.
+// Both are expected in normalised form.
+func MatchesDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// Param is a single query parameter, used where the order of added parameters matters
+type Param struct {
+	Key   string
+	Value string
+}
+
+// AddParams adds params to the query string of raw, keeping the existing
+// parameters in their original order and encoding. Parameters already present
+// are left alone unless override is set, in which case every existing
+// occurrence is replaced. Empty values are skipped.
+func AddParams(raw string, params []Param, override bool) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	existing, _ := url.ParseQuery(u.RawQuery)
+	query := u.RawQuery
+
+	var added []string
+	for _, p := range params {
+		if p.Value == "" {
+			continue
+		}
+		if _, ok := existing[p.Key]; ok {
+			if !override {
+				continue
+			}
+			query = RemoveQueryKey(query, p.Key)
+		}
+		added = append(added, url.QueryEscape(p.Key)+"="+url.QueryEscape(p.Value))
+	}
+	if len(added) == 0 {
+		return raw, nil
+	}
+
+	u.RawQuery = JoinQuery(query, strings.Join(added, "&"))
+	return u.String(), nil
+}
+
+// RemoveQueryKey drops every occurrence of key from a raw query string, leaving other pairs untouched
+func RemoveQueryKey(raw, key string) string {
+	pairs := strings.Split(raw, "&")
+	kept := pairs[:0]
+	for _, pair := range pairs {
+		name, _, _ := strings.Cut(pair, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil && decoded == key {
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	return strings.Join(kept, "&")
+}
+
+// JoinQuery concatenates two raw query strings
+func JoinQuery(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	default:
+		return a + "&" + b
+	}
+}
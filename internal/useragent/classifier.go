@@ -0,0 +1,66 @@
+package useragent
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/hohotang/shortlink-gateway/internal/filewatch"
+	"go.uber.org/zap"
+)
+
+// Classifier parses user agents into browser, OS and device and flags known bots.
+// Rules are loaded from a local YAML file and reloaded when the file changes.
+type Classifier struct {
+	path    string
+	rules   atomic.Pointer[ruleSet]
+	watcher *filewatch.Watcher
+	logger  *zap.Logger
+}
+
+// NewClassifier creates a classifier from the rules file at path. An empty path uses
+// the built-in rules. A positive reloadInterval enables hot reloading of the file.
+func NewClassifier(path string, reloadInterval time.Duration, logger *zap.Logger) (*Classifier, error) {
+	rules, err := loadRules(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Classifier{
+		path:   path,
+		logger: logger,
+	}
+	c.rules.Store(rules)
+
+	if path != "" {
+		c.watcher = filewatch.New(path, reloadInterval, c.reload)
+	}
+
+	return c, nil
+}
+
+// Classify returns the classification of a User-Agent header value
+func (c *Classifier) Classify(ua string) Info {
+	return c.rules.Load().classify(ua)
+}
+
+// reload swaps in the rules from disk, keeping the previous rules if the file is invalid
+func (c *Classifier) reload() {
+	rules, err := loadRules(c.path)
+	if err != nil {
+		c.logger.Error("Failed to reload user agent rules, keeping previous rules",
+			zap.String("path", c.path),
+			zap.Error(err),
+		)
+		return
+	}
+	c.rules.Store(rules)
+	c.logger.Info("Reloaded user agent rules", zap.String("path", c.path))
+}
+
+// Close stops watching the rules file
+func (c *Classifier) Close() error {
+	if c.watcher != nil {
+		return c.watcher.Close()
+	}
+	return nil
+}
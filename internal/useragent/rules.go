@@ -0,0 +1,125 @@
+package useragent
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed default_rules.yaml
+var defaultRules []byte
+
+// ruleFile mirrors the layout of a rules YAML file
+type ruleFile struct {
+	Bots             []ruleSpec `yaml:"bots"`
+	Browsers         []ruleSpec `yaml:"browsers"`
+	OperatingSystems []ruleSpec `yaml:"operating_systems"`
+	Devices          []ruleSpec `yaml:"devices"`
+}
+
+type ruleSpec struct {
+	Name    string `yaml:"name"`
+	Kind    string `yaml:"kind"`
+	Type    string `yaml:"type"`
+	Pattern string `yaml:"pattern"`
+	Exclude string `yaml:"exclude"`
+}
+
+// rule is a compiled ruleSpec
+type rule struct {
+	name    string
+	kind    BotKind
+	device  DeviceType
+	pattern *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// match reports whether the rule matches ua and returns the first capture group, if any
+func (r *rule) match(ua string) (bool, string) {
+	m := r.pattern.FindStringSubmatch(ua)
+	if m == nil {
+		return false, ""
+	}
+	if r.exclude != nil && r.exclude.MatchString(ua) {
+		return false, ""
+	}
+	if len(m) > 1 {
+		return true, m[1]
+	}
+	return true, ""
+}
+
+// ruleSet is an immutable, compiled set of classification rules
+type ruleSet struct {
+	bots     []rule
+	browsers []rule
+	systems  []rule
+	devices  []rule
+}
+
+// loadRules reads rules from path, or the built-in rules when path is empty
+func loadRules(path string) (*ruleSet, error) {
+	data := defaultRules
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read user agent rules: %w", err)
+		}
+	}
+	return parseRules(data)
+}
+
+func parseRules(data []byte) (*ruleSet, error) {
+	var file ruleFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse user agent rules: %w", err)
+	}
+
+	var (
+		set ruleSet
+		err error
+	)
+	if set.bots, err = compileRules("bots", file.Bots); err != nil {
+		return nil, err
+	}
+	if set.browsers, err = compileRules("browsers", file.Browsers); err != nil {
+		return nil, err
+	}
+	if set.systems, err = compileRules("operating_systems", file.OperatingSystems); err != nil {
+		return nil, err
+	}
+	if set.devices, err = compileRules("devices", file.Devices); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+func compileRules(section string, specs []ruleSpec) ([]rule, error) {
+	rules := make([]rule, 0, len(specs))
+	for i, spec := range specs {
+		pattern, err := regexp.Compile("(?i)" + spec.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: invalid pattern: %w", section, i, err)
+		}
+		r := rule{
+			name:    spec.Name,
+			kind:    BotKind(spec.Kind),
+			device:  DeviceType(spec.Type),
+			pattern: pattern,
+		}
+		if spec.Exclude != "" {
+			if r.exclude, err = regexp.Compile("(?i)" + spec.Exclude); err != nil {
+				return nil, fmt.Errorf("%s[%d]: invalid exclude pattern: %w", section, i, err)
+			}
+		}
+		if section == "bots" && r.kind == "" {
+			r.kind = BotKindCrawler
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
@@ -0,0 +1,126 @@
+package useragent
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DeviceType is the coarse device class of a client
+type DeviceType string
+
+const (
+	DeviceDesktop DeviceType = "desktop"
+	DeviceMobile  DeviceType = "mobile"
+	DeviceTablet  DeviceType = "tablet"
+	DeviceBot     DeviceType = "bot"
+	DeviceUnknown DeviceType = "unknown"
+)
+
+// BotKind describes why an automated client fetched a link
+type BotKind string
+
+const (
+	BotKindNone     BotKind = ""
+	BotKindUnfurler BotKind = "unfurler" // chat and social link previews
+	BotKindCrawler  BotKind = "crawler"  // search engines and other indexers
+	BotKindMonitor  BotKind = "monitor"  // uptime checks and security scanners
+	BotKindTool     BotKind = "tool"     // command line tools and HTTP libraries
+)
+
+// Info is the classification result for a single user agent
+type Info struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	Device         DeviceType
+	Bot            bool
+	BotName        string
+	BotKind        BotKind
+}
+
+// IsUnfurler reports whether the client only fetches links to render a preview
+func (i Info) IsUnfurler() bool {
+	return i.BotKind == BotKindUnfurler
+}
+
+// Attributes returns the classification as OpenTelemetry attributes for spans
+func (i Info) Attributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("user_agent.browser", i.Browser),
+		attribute.String("user_agent.os", i.OS),
+		attribute.String("user_agent.device", string(i.Device)),
+		attribute.Bool("user_agent.bot", i.Bot),
+	}
+	if i.Bot {
+		attrs = append(attrs,
+			attribute.String("user_agent.bot_name", i.BotName),
+			attribute.String("user_agent.bot_kind", string(i.BotKind)),
+		)
+	}
+	return attrs
+}
+
+// classify applies the rule set to a user agent string
+func (s *ruleSet) classify(ua string) Info {
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return Info{Device: DeviceUnknown}
+	}
+
+	var info Info
+
+	for i := range s.bots {
+		if ok, _ := s.bots[i].match(ua); ok {
+			info.Bot = true
+			info.BotName = s.bots[i].name
+			info.BotKind = s.bots[i].kind
+			break
+		}
+	}
+
+	for i := range s.browsers {
+		if ok, version := s.browsers[i].match(ua); ok {
+			info.Browser = s.browsers[i].name
+			info.BrowserVersion = version
+			break
+		}
+	}
+
+	for i := range s.systems {
+		if ok, version := s.systems[i].match(ua); ok {
+			info.OS = s.systems[i].name
+			info.OSVersion = strings.ReplaceAll(version, "_", ".")
+			break
+		}
+	}
+
+	switch {
+	case info.Bot:
+		info.Device = DeviceBot
+	default:
+		info.Device = DeviceDesktop
+		for i := range s.devices {
+			if ok, _ := s.devices[i].match(ua); ok {
+				info.Device = s.devices[i].device
+				break
+			}
+		}
+	}
+
+	return info
+}
+
+// IsPrefetch reports whether a request is a speculative prefetch or preview rather
+// than a user navigation, based on the purpose headers sent by browsers
+func IsPrefetch(header http.Header) bool {
+	for _, name := range []string{"Sec-Purpose", "Purpose", "X-Purpose", "X-Moz"} {
+		value := strings.ToLower(header.Get(name))
+		if strings.Contains(value, "prefetch") || strings.Contains(value, "preview") {
+			return true
+		}
+	}
+	return false
+}
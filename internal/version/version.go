@@ -0,0 +1,37 @@
+// Package version describes the running build
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time with -ldflags "-X github.com/hohotang/shortlink-gateway/internal/version.Version=..."
+// When empty they are taken from the build information embedded by the go tool.
+var (
+	Version string
+	Commit  string
+)
+
+// GoVersion is the Go release the binary was built with
+var GoVersion = runtime.Version()
+
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if Version == "" {
+		Version = "dev"
+		if ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			Version = info.Main.Version
+		}
+	}
+	if Commit == "" && ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				Commit = setting.Value
+			}
+		}
+	}
+	if Commit == "" {
+		Commit = "unknown"
+	}
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// deadLetters appends deliveries that ran out of attempts to a JSON lines file,
+// so that they survive restarts and can be replayed
+type deadLetters struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// write appends d to the file, creating it on first use
+func (l *deadLetters) write(d Delivery) error {
+	if l.path == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return err
+		}
+		l.file = f
+	}
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+// find returns the last record of the delivery with the given ID
+func (l *deadLetters) find(id string) (Delivery, bool, error) {
+	if l.path == "" {
+		return Delivery{}, false, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Delivery{}, false, nil
+	}
+	if err != nil {
+		return Delivery{}, false, err
+	}
+	defer f.Close()
+
+	var found Delivery
+	ok := false
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var d Delivery
+		if json.Unmarshal(scanner.Bytes(), &d) == nil && d.ID == id {
+			found, ok = d, true
+		}
+	}
+	return found, ok, scanner.Err()
+}
+
+func (l *deadLetters) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,612 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hohotang/shortlink-gateway/internal/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNotFound means the subscription or delivery does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrPending means a delivery cannot be replayed while it is still being attempted
+	ErrPending = errors.New("delivery is still pending")
+	// ErrClosed means the dispatcher no longer accepts deliveries
+	ErrClosed = errors.New("webhook dispatcher closed")
+	// ErrScheduleFull means the maximum number of scheduled events is reached
+	ErrScheduleFull = errors.New("too many scheduled webhook events")
+)
+
+// Status is the state of a delivery
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // queued or waiting for a retry
+	StatusSucceeded Status = "succeeded" // the endpoint answered with a 2xx status
+	StatusDead      Status = "dead"      // attempts exhausted or not retryable, written to the dead letter file
+)
+
+// Delivery tracks sending one event to one subscription
+type Delivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      EventType       `json:"event_type"`
+	Status         Status          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"` // status of the last response
+	Error          string          `json:"error,omitempty"`           // why the last attempt failed
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	ReplayOf       string          `json:"replay_of,omitempty"` // delivery this one replays
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+}
+
+// Config configures a Dispatcher
+type Config struct {
+	SubscriptionsPath string        // JSON file subscriptions are stored in, empty keeps them in memory only
+	DeadLetterPath    string        // JSON lines file of deliveries that were given up, empty disables it
+	Workers           int           // concurrent deliveries
+	QueueSize         int           // deliveries waiting for a worker, more are dead-lettered
+	MaxAttempts       int           // attempts before a delivery is given up
+	InitialBackoff    time.Duration // wait before the first retry, doubled for every further retry
+	MaxBackoff        time.Duration // longest wait between attempts
+	Timeout           time.Duration // timeout of a single attempt
+	LogSize           int           // deliveries kept in memory per subscription
+	MaxScheduled      int           // events waiting to be published later
+}
+
+// Dispatcher delivers signed events to subscribed endpoints. Deliveries are
+// attempted by a pool of workers and retried with exponential backoff; those
+// that cannot be delivered are appended to a dead letter file.
+type Dispatcher struct {
+	cfg     Config
+	subs    *subscriptionStore
+	dead    *deadLetters
+	client  *http.Client
+	metrics *otel.Metrics
+	logger  *zap.Logger
+
+	queue   chan *Delivery
+	stop    chan struct{}
+	workers sync.WaitGroup
+
+	mu         sync.Mutex
+	closed     bool
+	deliveries map[string]*Delivery      // the delivery log by ID
+	log        map[string][]string       // delivery IDs per subscription, oldest first
+	retries    map[*Delivery]*time.Timer // deliveries waiting for their next attempt
+	scheduled  map[*time.Timer]struct{}  // events to be published later
+}
+
+// New loads the subscriptions and starts the delivery workers
+func New(cfg Config, metrics *otel.Metrics, logger *zap.Logger) (*Dispatcher, error) {
+	subs, err := loadSubscriptions(cfg.SubscriptionsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Workers = max(cfg.Workers, 1)
+	cfg.QueueSize = max(cfg.QueueSize, 1)
+	cfg.MaxAttempts = max(cfg.MaxAttempts, 1)
+	cfg.LogSize = max(cfg.LogSize, 1)
+	cfg.MaxScheduled = max(cfg.MaxScheduled, 1)
+
+	d := &Dispatcher{
+		cfg:  cfg,
+		subs: subs,
+		dead: &deadLetters{path: cfg.DeadLetterPath},
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		metrics:    metrics,
+		logger:     logger,
+		queue:      make(chan *Delivery, cfg.QueueSize),
+		stop:       make(chan struct{}),
+		deliveries: make(map[string]*Delivery),
+		log:        make(map[string][]string),
+		retries:    make(map[*Delivery]*time.Timer),
+		scheduled:  make(map[*time.Timer]struct{}),
+	}
+
+	for i := 0; i < cfg.Workers; i++ {
+		d.workers.Add(1)
+		go d.work()
+	}
+	return d, nil
+}
+
+// Subscribe validates and stores a new subscription. The returned subscription
+// carries the secret, which is not returned again afterwards.
+func (d *Dispatcher) Subscribe(sub Subscription) (Subscription, error) {
+	if err := sub.validate(); err != nil {
+		return Subscription{}, err
+	}
+	if err := d.subs.add(sub); err != nil {
+		return Subscription{}, err
+	}
+	return sub, nil
+}
+
+// Unsubscribe deletes a subscription and its delivery log
+func (d *Dispatcher) Unsubscribe(id string) (bool, error) {
+	ok, err := d.subs.remove(id)
+	if !ok || err != nil {
+		return ok, err
+	}
+
+	d.mu.Lock()
+	for _, deliveryID := range d.log[id] {
+		delete(d.deliveries, deliveryID)
+	}
+	delete(d.log, id)
+	d.mu.Unlock()
+	return true, nil
+}
+
+// Subscription returns a subscription without its secret
+func (d *Dispatcher) Subscription(id string) (Subscription, bool) {
+	sub, ok := d.subs.get(id)
+	return sub.Redacted(), ok
+}
+
+// Subscriptions returns all subscriptions without their secrets, oldest first
+func (d *Dispatcher) Subscriptions() []Subscription {
+	subs := d.subs.list()
+	for i := range subs {
+		subs[i] = subs[i].Redacted()
+	}
+	return subs
+}
+
+// Publish sends an event to every subscription that wants it
+func (d *Dispatcher) Publish(t EventType, data LinkData) {
+	event, payload, err := newEvent(t, data)
+	if err != nil {
+		d.logger.Error("Failed to encode webhook event", zap.String("event", string(t)), zap.Error(err))
+		return
+	}
+
+	for _, sub := range d.subs.list() {
+		if sub.Wants(t) {
+			d.enqueue(sub, event, payload, "")
+		}
+	}
+}
+
+// PublishAt publishes an event at a later time. Scheduled events are kept in
+// memory only and are lost when the gateway stops. Nothing is scheduled when no
+// subscription wants the event yet, and ErrScheduleFull is returned once
+// MaxScheduled events are waiting.
+func (d *Dispatcher) PublishAt(at time.Time, t EventType, data LinkData) error {
+	if !slices.ContainsFunc(d.subs.list(), func(sub Subscription) bool { return sub.Wants(t) }) {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return ErrClosed
+	}
+	if len(d.scheduled) >= d.cfg.MaxScheduled {
+		return ErrScheduleFull
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(at), func() {
+		d.mu.Lock()
+		_, ok := d.scheduled[timer]
+		delete(d.scheduled, timer)
+		d.mu.Unlock()
+
+		if ok {
+			d.Publish(t, data)
+		}
+	})
+	d.scheduled[timer] = struct{}{}
+	return nil
+}
+
+// Clicks publishes link.click_threshold to the subscriptions with a threshold
+// equal to data.Clicks, the link's click count after a click was recorded
+func (d *Dispatcher) Clicks(data LinkData) {
+	subs := d.subs.withThreshold(data.Clicks)
+	if len(subs) == 0 {
+		return
+	}
+
+	data.Threshold = data.Clicks
+	event, payload, err := newEvent(EventClickThreshold, data)
+	if err != nil {
+		d.logger.Error("Failed to encode webhook event", zap.String("event", string(EventClickThreshold)), zap.Error(err))
+		return
+	}
+	for _, sub := range subs {
+		d.enqueue(sub, event, payload, "")
+	}
+}
+
+// Ping sends a test event to a single subscription
+func (d *Dispatcher) Ping(id string) (Delivery, error) {
+	sub, ok := d.subs.get(id)
+	if !ok {
+		return Delivery{}, ErrNotFound
+	}
+
+	event, payload, err := newEvent(EventPing, LinkData{})
+	if err != nil {
+		return Delivery{}, err
+	}
+	return d.enqueue(sub, event, payload, "")
+}
+
+// Deliveries returns the logged deliveries of a subscription, newest first. An
+// empty status returns deliveries in any state, a non-positive limit all of them.
+func (d *Dispatcher) Deliveries(subscriptionID string, status Status, limit int) []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ids := d.log[subscriptionID]
+	out := make([]Delivery, 0)
+	for i := len(ids) - 1; i >= 0; i-- {
+		del := d.deliveries[ids[i]]
+		if status != "" && del.Status != status {
+			continue
+		}
+		out = append(out, *del)
+		if limit > 0 && len(out) == limit {
+			break
+		}
+	}
+	return out
+}
+
+// Replay sends the event of a finished delivery of a subscription again as a
+// new delivery. The delivery is looked up in the log and then in the dead
+// letter file, so dead letters can be replayed after a restart.
+func (d *Dispatcher) Replay(subscriptionID, id string) (Delivery, error) {
+	d.mu.Lock()
+	del, ok := d.deliveries[id]
+	var original Delivery
+	if ok {
+		original = *del
+	}
+	d.mu.Unlock()
+
+	if !ok {
+		var err error
+		original, ok, err = d.dead.find(id)
+		if err != nil {
+			return Delivery{}, err
+		}
+		if !ok {
+			return Delivery{}, ErrNotFound
+		}
+	}
+	if original.SubscriptionID != subscriptionID {
+		return Delivery{}, ErrNotFound
+	}
+	if original.Status == StatusPending {
+		return Delivery{}, ErrPending
+	}
+
+	sub, ok := d.subs.get(original.SubscriptionID)
+	if !ok {
+		return Delivery{}, ErrNotFound
+	}
+	event := Event{ID: original.EventID, Type: original.EventType}
+	return d.enqueue(sub, event, original.Payload, original.ID)
+}
+
+// Close stops the workers after their current attempt. Deliveries that are
+// still queued or waiting for a retry are dead-lettered so they can be replayed.
+func (d *Dispatcher) Close() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+
+	var pending []*Delivery
+	for del, timer := range d.retries {
+		// A timer that already fired pushes its delivery, which is then given up
+		if timer.Stop() {
+			pending = append(pending, del)
+		}
+	}
+	clear(d.retries)
+	for timer := range d.scheduled {
+		timer.Stop()
+	}
+	clear(d.scheduled)
+	d.mu.Unlock()
+
+	close(d.stop)
+	d.workers.Wait()
+
+drain:
+	for {
+		select {
+		case del := <-d.queue:
+			pending = append(pending, del)
+		default:
+			break drain
+		}
+	}
+	for _, del := range pending {
+		d.finish(del, StatusDead, del.ResponseStatus, "gateway shut down before delivery")
+	}
+
+	return d.dead.Close()
+}
+
+func newEvent(t EventType, data LinkData) (Event, []byte, error) {
+	event := Event{
+		ID:        newID("evt_"),
+		Type:      t,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	return event, payload, err
+}
+
+// enqueue logs a new delivery of the event to sub and queues it for a worker
+func (d *Dispatcher) enqueue(sub Subscription, event Event, payload []byte, replayOf string) (Delivery, error) {
+	now := time.Now().UTC()
+	del := &Delivery{
+		ID:             newID("dlv_"),
+		SubscriptionID: sub.ID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		Status:         StatusPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		ReplayOf:       replayOf,
+		Payload:        payload,
+	}
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return Delivery{}, ErrClosed
+	}
+	d.deliveries[del.ID] = del
+	ids := append(d.log[sub.ID], del.ID)
+	if len(ids) > d.cfg.LogSize {
+		// Queued deliveries keep their own reference, only the log entry goes
+		delete(d.deliveries, ids[0])
+		ids = ids[1:]
+	}
+	d.log[sub.ID] = ids
+	snapshot := *del
+	d.mu.Unlock()
+
+	d.push(del)
+	return snapshot, nil
+}
+
+// push hands a delivery to the workers, giving it up if the queue is full or
+// the dispatcher is closed
+func (d *Dispatcher) push(del *Delivery) {
+	d.mu.Lock()
+	reason := ""
+	if d.closed {
+		reason = "gateway shut down before delivery"
+	} else {
+		select {
+		case d.queue <- del:
+		default:
+			reason = "delivery queue is full"
+		}
+	}
+	d.mu.Unlock()
+
+	if reason != "" {
+		d.finish(del, StatusDead, del.ResponseStatus, reason)
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.workers.Done()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case del := <-d.queue:
+			select {
+			case <-d.stop:
+				d.finish(del, StatusDead, del.ResponseStatus, "gateway shut down before delivery")
+				return
+			default:
+			}
+			d.attempt(del)
+		}
+	}
+}
+
+// attempt sends a delivery once and decides whether it succeeded, is retried or given up
+func (d *Dispatcher) attempt(del *Delivery) {
+	d.mu.Lock()
+	del.Attempts++
+	attempts := del.Attempts
+	d.mu.Unlock()
+
+	sub, ok := d.subs.get(del.SubscriptionID)
+	if !ok {
+		d.finish(del, StatusDead, 0, "subscription was deleted")
+		return
+	}
+
+	status, retryAfter, err := d.send(sub, del)
+	switch {
+	case err == nil && status >= 200 && status < 300:
+		d.finish(del, StatusSucceeded, status, "")
+		return
+	case err == nil:
+		err = fmt.Errorf("endpoint answered %d", status)
+	}
+
+	if !retryable(status) || attempts >= d.cfg.MaxAttempts {
+		d.finish(del, StatusDead, status, err.Error())
+		return
+	}
+	d.retry(del, status, err.Error(), max(d.backoff(attempts), min(retryAfter, d.cfg.MaxBackoff)))
+}
+
+// send posts the payload with signature headers and returns the response status
+// and the Retry-After delay the endpoint asked for
+func (d *Dispatcher) send(sub Subscription, del *Delivery) (int, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(del.Payload))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "shortlink-gateway-webhook/1.0")
+	req.Header.Set(HeaderEvent, string(del.EventType))
+	req.Header.Set(HeaderDelivery, del.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, del.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	return resp.StatusCode, retryAfter(resp.Header.Get("Retry-After")), nil
+}
+
+// retry schedules the next attempt of a delivery after wait
+func (d *Dispatcher) retry(del *Delivery, status int, reason string, wait time.Duration) {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		d.finish(del, StatusDead, status, reason)
+		return
+	}
+
+	now := time.Now().UTC()
+	next := now.Add(wait)
+	del.ResponseStatus = status
+	del.Error = reason
+	del.UpdatedAt = now
+	del.NextAttemptAt = &next
+	d.retries[del] = time.AfterFunc(wait, func() {
+		d.mu.Lock()
+		delete(d.retries, del)
+		d.mu.Unlock()
+		d.push(del)
+	})
+	d.mu.Unlock()
+
+	d.logger.Debug("Webhook delivery failed, retrying",
+		zap.String("delivery", del.ID),
+		zap.String("subscription", del.SubscriptionID),
+		zap.Duration("wait", wait),
+		zap.String("reason", reason),
+	)
+	d.record(del.EventType, "retried")
+}
+
+// finish records the final state of a delivery and dead-letters given up deliveries
+func (d *Dispatcher) finish(del *Delivery, status Status, responseStatus int, reason string) {
+	d.mu.Lock()
+	del.Status = status
+	del.ResponseStatus = responseStatus
+	del.Error = reason
+	del.UpdatedAt = time.Now().UTC()
+	del.NextAttemptAt = nil
+	snapshot := *del
+	d.mu.Unlock()
+
+	d.record(del.EventType, string(status))
+	if status != StatusDead {
+		return
+	}
+
+	d.logger.Warn("Webhook delivery given up",
+		zap.String("delivery", snapshot.ID),
+		zap.String("subscription", snapshot.SubscriptionID),
+		zap.String("event", string(snapshot.EventType)),
+		zap.Int("attempts", snapshot.Attempts),
+		zap.String("reason", reason),
+	)
+	if err := d.dead.write(snapshot); err != nil {
+		d.logger.Error("Failed to write webhook dead letter", zap.String("delivery", snapshot.ID), zap.Error(err))
+	}
+}
+
+func (d *Dispatcher) record(t EventType, result string) {
+	if d.metrics == nil {
+		return
+	}
+	d.metrics.WebhookDeliveries.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("event", string(t)),
+		attribute.String("result", result),
+	))
+}
+
+// backoff returns the wait before the retry following the given attempt: the
+// initial backoff doubled per attempt up to the maximum, with random jitter
+// of up to half of it so that retries of many deliveries spread out
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.cfg.InitialBackoff
+	for i := 1; i < attempts && wait < d.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	wait = min(wait, d.cfg.MaxBackoff)
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + rand.N(wait/2+1)
+}
+
+// retryable reports whether a failed attempt with the response status is worth
+// retrying; 0 stands for a network error
+func retryable(status int) bool {
+	return status == 0 || status >= 500 || slices.Contains([]int{
+		http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests,
+	}, status)
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hohotang/shortlink-gateway/internal/urlutil"
+)
+
+// ErrInvalidSubscription is returned for subscriptions that cannot be created
+var ErrInvalidSubscription = errors.New("invalid subscription")
+
+// Subscription sends events to an HTTP endpoint
+type Subscription struct {
+	ID              string      `json:"id"`
+	URL             string      `json:"url"`
+	Events          []EventType `json:"events,omitempty"`           // empty receives every event
+	ClickThresholds []int64     `json:"click_thresholds,omitempty"` // human click counts that trigger link.click_threshold
+	Secret          string      `json:"secret,omitempty"`           // HMAC key of the signatures, only returned on creation
+	Description     string      `json:"description,omitempty"`
+	CreatedAt       time.Time   `json:"created_at"`
+}
+
+// Wants reports whether the subscription receives events of type t
+func (s *Subscription) Wants(t EventType) bool {
+	return len(s.Events) == 0 || slices.Contains(s.Events, t)
+}
+
+// Redacted returns a copy of the subscription without its secret
+func (s Subscription) Redacted() Subscription {
+	s.Secret = ""
+	return s
+}
+
+// validate checks a new subscription and fills in its ID, secret and creation time
+func (s *Subscription) validate() error {
+	u, err := urlutil.Normalize(s.URL)
+	if err != nil {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidSubscription)
+	}
+	s.URL = u.String()
+
+	for _, event := range s.Events {
+		if !slices.Contains(EventTypes, event) {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidSubscription, event)
+		}
+	}
+	for _, threshold := range s.ClickThresholds {
+		if threshold <= 0 {
+			return fmt.Errorf("%w: click thresholds must be positive", ErrInvalidSubscription)
+		}
+	}
+	if len(s.ClickThresholds) > 0 && !s.Wants(EventClickThreshold) {
+		return fmt.Errorf("%w: click thresholds need the %s event", ErrInvalidSubscription, EventClickThreshold)
+	}
+
+	s.ID = newID("sub_")
+	if s.Secret == "" {
+		s.Secret = "whsec_" + randomString(32)
+	}
+	s.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// subscriptionStore keeps subscriptions in memory and, when path is set, in a JSON file
+type subscriptionStore struct {
+	mu   sync.RWMutex
+	byID map[string]*Subscription
+	path string
+}
+
+func loadSubscriptions(path string) (*subscriptionStore, error) {
+	s := &subscriptionStore{byID: make(map[string]*Subscription), path: path}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []*Subscription
+	if err := json.Unmarshal(data, &subs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, sub := range subs {
+		s.byID[sub.ID] = sub
+	}
+	return s, nil
+}
+
+func (s *subscriptionStore) add(sub Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.byID[sub.ID] = &sub
+	if err := s.save(); err != nil {
+		delete(s.byID, sub.ID)
+		return err
+	}
+	return nil
+}
+
+func (s *subscriptionStore) remove(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.byID[id]
+	if !ok {
+		return false, nil
+	}
+	delete(s.byID, id)
+	if err := s.save(); err != nil {
+		s.byID[id] = sub
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *subscriptionStore) get(id string) (Subscription, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if sub, ok := s.byID[id]; ok {
+		return *sub, true
+	}
+	return Subscription{}, false
+}
+
+// withThreshold returns the subscriptions to link.click_threshold with the given threshold
+func (s *subscriptionStore) withThreshold(clicks int64) []Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var subs []Subscription
+	for _, sub := range s.byID {
+		if sub.Wants(EventClickThreshold) && slices.Contains(sub.ClickThresholds, clicks) {
+			subs = append(subs, *sub)
+		}
+	}
+	return subs
+}
+
+// list returns all subscriptions, oldest first
+func (s *subscriptionStore) list() []Subscription {
+	s.mu.RLock()
+	subs := make([]Subscription, 0, len(s.byID))
+	for _, sub := range s.byID {
+		subs = append(subs, *sub)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].CreatedAt.Equal(subs[j].CreatedAt) {
+			return subs[i].ID < subs[j].ID
+		}
+		return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+	})
+	return subs
+}
+
+// save replaces the subscriptions file, the caller must hold the lock
+func (s *subscriptionStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	subs := make([]*Subscription, 0, len(s.byID))
+	for _, sub := range s.byID {
+		subs = append(subs, sub)
+	}
+	data, err := json.MarshalIndent(subs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	// The file holds the signing secrets, so it is only readable by the gateway
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// newID returns a random identifier with the given prefix
+func newID(prefix string) string {
+	return prefix + randomString(12)
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
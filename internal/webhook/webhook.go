@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// EventType names a link lifecycle event
+type EventType string
+
+const (
+	EventLinkCreated    EventType = "link.created"         // a new short link was created
+	EventLinkUpdated    EventType = "link.updated"         // a short link was changed through the gateway, such as a new signed URL
+	EventLinkExpired    EventType = "link.expired"         // a signed URL of a short link expired
+	EventClickThreshold EventType = "link.click_threshold" // a short link reached one of the subscription's click thresholds
+	EventPing           EventType = "webhook.ping"         // test event sent on request, to one subscription only
+)
+
+// EventTypes lists the events a subscription can choose from
+var EventTypes = []EventType{EventLinkCreated, EventLinkUpdated, EventLinkExpired, EventClickThreshold}
+
+// Event is the JSON body of every delivery
+type Event struct {
+	ID        string    `json:"id"`
+	Type      EventType `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      LinkData  `json:"data"`
+}
+
+// LinkData describes the link an event is about
+type LinkData struct {
+	ShortID     string     `json:"short_id,omitempty"`
+	ShortURL    string     `json:"short_url,omitempty"`
+	OriginalURL string     `json:"original_url,omitempty"`
+	Owner       string     `json:"owner,omitempty"`      // opaque ID of the caller that created the link
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // expiry of the signed URL, for link.updated and link.expired
+	KeyID       string     `json:"key_id,omitempty"`     // key the signed URL was signed with
+	Clicks      int64      `json:"clicks,omitempty"`     // clicks counted when the threshold was reached
+	Threshold   int64      `json:"threshold,omitempty"`  // the threshold that was reached
+}
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Shortlink-Event"
+	HeaderDelivery  = "X-Shortlink-Delivery"
+	HeaderTimestamp = "X-Shortlink-Timestamp"
+	HeaderSignature = "X-Shortlink-Signature"
+)
+
+var (
+	// ErrSignature means a delivery signature does not match its body
+	ErrSignature = errors.New("webhook signature mismatch")
+	// ErrTimestamp means a delivery was signed too long ago, or in the future
+	ErrTimestamp = errors.New("webhook timestamp outside tolerance")
+)
+
+// Sign returns the signature header of a delivery with body sent at timestamp:
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body))
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature and timestamp headers of a received delivery.
+// Receivers should use it to reject forged and replayed requests.
+func Verify(secret, signature, timestamp string, body []byte, now time.Time, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrTimestamp
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return ErrTimestamp
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, ts, body))) {
+		return ErrSignature
+	}
+	return nil
+}
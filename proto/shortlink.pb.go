@@ -21,17 +21,391 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// ShortenURLRequest contains the original URL to shorten
-type ShortenURLRequest struct {
+// Stickiness controls whether a visitor keeps seeing the same split variant
+type Stickiness int32
+
+const (
+	Stickiness_STICKINESS_NONE   Stickiness = 0 // pick a variant on every visit
+	Stickiness_STICKINESS_COOKIE Stickiness = 1 // remember the visitor with a cookie
+	Stickiness_STICKINESS_HASH   Stickiness = 2 // derive the visitor from a hash of IP and user agent
+)
+
+// Enum value maps for Stickiness.
+var (
+	Stickiness_name = map[int32]string{
+		0: "STICKINESS_NONE",
+		1: "STICKINESS_COOKIE",
+		2: "STICKINESS_HASH",
+	}
+	Stickiness_value = map[string]int32{
+		"STICKINESS_NONE":   0,
+		"STICKINESS_COOKIE": 1,
+		"STICKINESS_HASH":   2,
+	}
+)
+
+func (x Stickiness) Enum() *Stickiness {
+	p := new(Stickiness)
+	*p = x
+	return p
+}
+
+func (x Stickiness) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Stickiness) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_shortlink_proto_enumTypes[0].Descriptor()
+}
+
+func (Stickiness) Type() protoreflect.EnumType {
+	return &file_proto_shortlink_proto_enumTypes[0]
+}
+
+func (x Stickiness) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Stickiness.Descriptor instead.
+func (Stickiness) EnumDescriptor() ([]byte, []int) {
+	return file_proto_shortlink_proto_rawDescGZIP(), []int{0}
+}
+
+// QueryMode controls whether the query string of the short URL is forwarded to the destination
+type QueryMode int32
+
+const (
+	QueryMode_QUERY_MODE_DEFAULT  QueryMode = 0 // use the gateway's configured mode
+	QueryMode_QUERY_MODE_OFF      QueryMode = 1 // do not forward
+	QueryMode_QUERY_MODE_KEEP     QueryMode = 2 // forward, the destination's value wins for conflicting keys
+	QueryMode_QUERY_MODE_OVERRIDE QueryMode = 3 // forward, the incoming value wins for conflicting keys
+	QueryMode_QUERY_MODE_APPEND   QueryMode = 4 // forward, conflicting keys keep both values
+)
+
+// Enum value maps for QueryMode.
+var (
+	QueryMode_name = map[int32]string{
+		0: "QUERY_MODE_DEFAULT",
+		1: "QUERY_MODE_OFF",
+		2: "QUERY_MODE_KEEP",
+		3: "QUERY_MODE_OVERRIDE",
+		4: "QUERY_MODE_APPEND",
+	}
+	QueryMode_value = map[string]int32{
+		"QUERY_MODE_DEFAULT":  0,
+		"QUERY_MODE_OFF":      1,
+		"QUERY_MODE_KEEP":     2,
+		"QUERY_MODE_OVERRIDE": 3,
+		"QUERY_MODE_APPEND":   4,
+	}
+)
+
+func (x QueryMode) Enum() *QueryMode {
+	p := new(QueryMode)
+	*p = x
+	return p
+}
+
+func (x QueryMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (QueryMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_shortlink_proto_enumTypes[1].Descriptor()
+}
+
+func (QueryMode) Type() protoreflect.EnumType {
+	return &file_proto_shortlink_proto_enumTypes[1]
+}
+
+func (x QueryMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use QueryMode.Descriptor instead.
+func (QueryMode) EnumDescriptor() ([]byte, []int) {
+	return file_proto_shortlink_proto_rawDescGZIP(), []int{1}
+}
+
+// FragmentMode controls which fragment the visitor ends up on
+type FragmentMode int32
+
+const (
+	FragmentMode_FRAGMENT_MODE_DEFAULT     FragmentMode = 0 // use the gateway's configured mode
+	FragmentMode_FRAGMENT_MODE_DESTINATION FragmentMode = 1 // keep the destination's fragment
+	FragmentMode_FRAGMENT_MODE_VISITOR     FragmentMode = 2 // drop it so browsers carry over the short URL's fragment
+)
+
+// Enum value maps for FragmentMode.
+var (
+	FragmentMode_name = map[int32]string{
+		0: "FRAGMENT_MODE_DEFAULT",
+		1: "FRAGMENT_MODE_DESTINATION",
+		2: "FRAGMENT_MODE_VISITOR",
+	}
+	FragmentMode_value = map[string]int32{
+		"FRAGMENT_MODE_DEFAULT":     0,
+		"FRAGMENT_MODE_DESTINATION": 1,
+		"FRAGMENT_MODE_VISITOR":     2,
+	}
+)
+
+func (x FragmentMode) Enum() *FragmentMode {
+	p := new(FragmentMode)
+	*p = x
+	return p
+}
+
+func (x FragmentMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FragmentMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_shortlink_proto_enumTypes[2].Descriptor()
+}
+
+func (FragmentMode) Type() protoreflect.EnumType {
+	return &file_proto_shortlink_proto_enumTypes[2]
+}
+
+func (x FragmentMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FragmentMode.Descriptor instead.
+func (FragmentMode) EnumDescriptor() ([]byte, []int) {
+	return file_proto_shortlink_proto_rawDescGZIP(), []int{2}
+}
+
+// RedirectRule sends visitors matching all of its non-empty conditions to an
+// alternative destination. Rules are evaluated in order at redirect time and the
+// first match wins; when nothing matches the original URL is used.
+type RedirectRule struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Countries      []string               `protobuf:"bytes,1,rep,name=countries,proto3" json:"countries,omitempty"`                   // ISO 3166-1 alpha-2 country codes
+	DeviceOs       []string               `protobuf:"bytes,2,rep,name=device_os,json=deviceOs,proto3" json:"device_os,omitempty"`     // client operating systems, e.g. "iOS", "Android"
+	Languages      []string               `protobuf:"bytes,3,rep,name=languages,proto3" json:"languages,omitempty"`                   // primary language subtags, e.g. "en", "zh"
+	NotBefore      int64                  `protobuf:"varint,4,opt,name=not_before,json=notBefore,proto3" json:"not_before,omitempty"` // unix seconds, 0 means no lower bound
+	NotAfter       int64                  `protobuf:"varint,5,opt,name=not_after,json=notAfter,proto3" json:"not_after,omitempty"`    // unix seconds, 0 means no upper bound
+	DestinationUrl string                 `protobuf:"bytes,6,opt,name=destination_url,json=destinationUrl,proto3" json:"destination_url,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RedirectRule) Reset() {
+	*x = RedirectRule{}
+	mi := &file_proto_shortlink_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RedirectRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RedirectRule) ProtoMessage() {}
+
+func (x *RedirectRule) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortlink_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RedirectRule.ProtoReflect.Descriptor instead.
+func (*RedirectRule) Descriptor() ([]byte, []int) {
+	return file_proto_shortlink_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RedirectRule) GetCountries() []string {
+	if x != nil {
+		return x.Countries
+	}
+	return nil
+}
+
+func (x *RedirectRule) GetDeviceOs() []string {
+	if x != nil {
+		return x.DeviceOs
+	}
+	return nil
+}
+
+func (x *RedirectRule) GetLanguages() []string {
+	if x != nil {
+		return x.Languages
+	}
+	return nil
+}
+
+func (x *RedirectRule) GetNotBefore() int64 {
+	if x != nil {
+		return x.NotBefore
+	}
+	return 0
+}
+
+func (x *RedirectRule) GetNotAfter() int64 {
+	if x != nil {
+		return x.NotAfter
+	}
+	return 0
+}
+
+func (x *RedirectRule) GetDestinationUrl() string {
+	if x != nil {
+		return x.DestinationUrl
+	}
+	return ""
+}
+
+// Variant is one weighted destination of an A/B split link
+type Variant struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Name           string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	DestinationUrl string                 `protobuf:"bytes,2,opt,name=destination_url,json=destinationUrl,proto3" json:"destination_url,omitempty"`
+	Weight         uint32                 `protobuf:"varint,3,opt,name=weight,proto3" json:"weight,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Variant) Reset() {
+	*x = Variant{}
+	mi := &file_proto_shortlink_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Variant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Variant) ProtoMessage() {}
+
+func (x *Variant) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortlink_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Variant.ProtoReflect.Descriptor instead.
+func (*Variant) Descriptor() ([]byte, []int) {
+	return file_proto_shortlink_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Variant) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Variant) GetDestinationUrl() string {
+	if x != nil {
+		return x.DestinationUrl
+	}
+	return ""
+}
+
+func (x *Variant) GetWeight() uint32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+// RedirectOptions controls how visitors are redirected, zero values use the gateway defaults
+type RedirectOptions struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	OriginalUrl   string                 `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	Status        int32                  `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"` // 301, 302, 307 or 308
+	QueryMode     QueryMode              `protobuf:"varint,2,opt,name=query_mode,json=queryMode,proto3,enum=shortlink.QueryMode" json:"query_mode,omitempty"`
+	FragmentMode  FragmentMode           `protobuf:"varint,3,opt,name=fragment_mode,json=fragmentMode,proto3,enum=shortlink.FragmentMode" json:"fragment_mode,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
+func (x *RedirectOptions) Reset() {
+	*x = RedirectOptions{}
+	mi := &file_proto_shortlink_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RedirectOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RedirectOptions) ProtoMessage() {}
+
+func (x *RedirectOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortlink_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RedirectOptions.ProtoReflect.Descriptor instead.
+func (*RedirectOptions) Descriptor() ([]byte, []int) {
+	return file_proto_shortlink_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RedirectOptions) GetStatus() int32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *RedirectOptions) GetQueryMode() QueryMode {
+	if x != nil {
+		return x.QueryMode
+	}
+	return QueryMode_QUERY_MODE_DEFAULT
+}
+
+func (x *RedirectOptions) GetFragmentMode() FragmentMode {
+	if x != nil {
+		return x.FragmentMode
+	}
+	return FragmentMode_FRAGMENT_MODE_DEFAULT
+}
+
+// ShortenURLRequest contains the original URL to shorten
+type ShortenURLRequest struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	OriginalUrl       string                 `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	Rules             []*RedirectRule        `protobuf:"bytes,2,rep,name=rules,proto3" json:"rules,omitempty"`       // optional targeting rules
+	Variants          []*Variant             `protobuf:"bytes,3,rep,name=variants,proto3" json:"variants,omitempty"` // optional A/B split destinations
+	Stickiness        Stickiness             `protobuf:"varint,4,opt,name=stickiness,proto3,enum=shortlink.Stickiness" json:"stickiness,omitempty"`
+	Password          string                 `protobuf:"bytes,5,opt,name=password,proto3" json:"password,omitempty"`                                             // optional, stored only as a hash by the core
+	SignatureRequired bool                   `protobuf:"varint,6,opt,name=signature_required,json=signatureRequired,proto3" json:"signature_required,omitempty"` // only signed, unexpired URLs may be expanded
+	Interstitial      bool                   `protobuf:"varint,7,opt,name=interstitial,proto3" json:"interstitial,omitempty"`                                    // warn visitors before redirecting them
+	Redirect          *RedirectOptions       `protobuf:"bytes,8,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	Owner             string                 `protobuf:"bytes,9,opt,name=owner,proto3" json:"owner,omitempty"`                                       // opaque ID of the caller that created the link
+	NormalizedUrl     string                 `protobuf:"bytes,10,opt,name=normalized_url,json=normalizedUrl,proto3" json:"normalized_url,omitempty"` // original_url in the gateway's canonical form, for FindURL
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
 func (x *ShortenURLRequest) Reset() {
 	*x = ShortenURLRequest{}
-	mi := &file_proto_shortlink_proto_msgTypes[0]
+	mi := &file_proto_shortlink_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -43,7 +417,7 @@ func (x *ShortenURLRequest) String() string {
 func (*ShortenURLRequest) ProtoMessage() {}
 
 func (x *ShortenURLRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortlink_proto_msgTypes[0]
+	mi := &file_proto_shortlink_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -56,7 +430,7 @@ func (x *ShortenURLRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShortenURLRequest.ProtoReflect.Descriptor instead.
 func (*ShortenURLRequest) Descriptor() ([]byte, []int) {
-	return file_proto_shortlink_proto_rawDescGZIP(), []int{0}
+	return file_proto_shortlink_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *ShortenURLRequest) GetOriginalUrl() string {
@@ -66,6 +440,69 @@ func (x *ShortenURLRequest) GetOriginalUrl() string {
 	return ""
 }
 
+func (x *ShortenURLRequest) GetRules() []*RedirectRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+func (x *ShortenURLRequest) GetVariants() []*Variant {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+func (x *ShortenURLRequest) GetStickiness() Stickiness {
+	if x != nil {
+		return x.Stickiness
+	}
+	return Stickiness_STICKINESS_NONE
+}
+
+func (x *ShortenURLRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *ShortenURLRequest) GetSignatureRequired() bool {
+	if x != nil {
+		return x.SignatureRequired
+	}
+	return false
+}
+
+func (x *ShortenURLRequest) GetInterstitial() bool {
+	if x != nil {
+		return x.Interstitial
+	}
+	return false
+}
+
+func (x *ShortenURLRequest) GetRedirect() *RedirectOptions {
+	if x != nil {
+		return x.Redirect
+	}
+	return nil
+}
+
+func (x *ShortenURLRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *ShortenURLRequest) GetNormalizedUrl() string {
+	if x != nil {
+		return x.NormalizedUrl
+	}
+	return ""
+}
+
 // ShortenURLResponse contains the generated short URL ID
 type ShortenURLResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -77,7 +514,7 @@ type ShortenURLResponse struct {
 
 func (x *ShortenURLResponse) Reset() {
 	*x = ShortenURLResponse{}
-	mi := &file_proto_shortlink_proto_msgTypes[1]
+	mi := &file_proto_shortlink_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -89,7 +526,7 @@ func (x *ShortenURLResponse) String() string {
 func (*ShortenURLResponse) ProtoMessage() {}
 
 func (x *ShortenURLResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortlink_proto_msgTypes[1]
+	mi := &file_proto_shortlink_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -102,7 +539,7 @@ func (x *ShortenURLResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShortenURLResponse.ProtoReflect.Descriptor instead.
 func (*ShortenURLResponse) Descriptor() ([]byte, []int) {
-	return file_proto_shortlink_proto_rawDescGZIP(), []int{1}
+	return file_proto_shortlink_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *ShortenURLResponse) GetShortId() string {
@@ -129,7 +566,7 @@ type ExpandURLRequest struct {
 
 func (x *ExpandURLRequest) Reset() {
 	*x = ExpandURLRequest{}
-	mi := &file_proto_shortlink_proto_msgTypes[2]
+	mi := &file_proto_shortlink_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -141,7 +578,7 @@ func (x *ExpandURLRequest) String() string {
 func (*ExpandURLRequest) ProtoMessage() {}
 
 func (x *ExpandURLRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortlink_proto_msgTypes[2]
+	mi := &file_proto_shortlink_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -154,7 +591,7 @@ func (x *ExpandURLRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExpandURLRequest.ProtoReflect.Descriptor instead.
 func (*ExpandURLRequest) Descriptor() ([]byte, []int) {
-	return file_proto_shortlink_proto_rawDescGZIP(), []int{2}
+	return file_proto_shortlink_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *ExpandURLRequest) GetShortId() string {
@@ -166,15 +603,23 @@ func (x *ExpandURLRequest) GetShortId() string {
 
 // ExpandURLResponse contains the original URL
 type ExpandURLResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	OriginalUrl   string                 `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	OriginalUrl       string                 `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	Rules             []*RedirectRule        `protobuf:"bytes,2,rep,name=rules,proto3" json:"rules,omitempty"`       // targeting rules stored with the link
+	Variants          []*Variant             `protobuf:"bytes,3,rep,name=variants,proto3" json:"variants,omitempty"` // A/B split destinations stored with the link
+	Stickiness        Stickiness             `protobuf:"varint,4,opt,name=stickiness,proto3,enum=shortlink.Stickiness" json:"stickiness,omitempty"`
+	PasswordProtected bool                   `protobuf:"varint,5,opt,name=password_protected,json=passwordProtected,proto3" json:"password_protected,omitempty"` // visitors must pass VerifyPassword before redirecting
+	SignatureRequired bool                   `protobuf:"varint,6,opt,name=signature_required,json=signatureRequired,proto3" json:"signature_required,omitempty"` // the gateway rejects unsigned expansions
+	Interstitial      bool                   `protobuf:"varint,7,opt,name=interstitial,proto3" json:"interstitial,omitempty"`                                    // the gateway shows a warning page before redirecting
+	CreatedAt         int64                  `protobuf:"varint,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`                         // unix seconds, 0 if unknown
+	Redirect          *RedirectOptions       `protobuf:"bytes,9,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *ExpandURLResponse) Reset() {
 	*x = ExpandURLResponse{}
-	mi := &file_proto_shortlink_proto_msgTypes[3]
+	mi := &file_proto_shortlink_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -186,7 +631,7 @@ func (x *ExpandURLResponse) String() string {
 func (*ExpandURLResponse) ProtoMessage() {}
 
 func (x *ExpandURLResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortlink_proto_msgTypes[3]
+	mi := &file_proto_shortlink_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -199,7 +644,7 @@ func (x *ExpandURLResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExpandURLResponse.ProtoReflect.Descriptor instead.
 func (*ExpandURLResponse) Descriptor() ([]byte, []int) {
-	return file_proto_shortlink_proto_rawDescGZIP(), []int{3}
+	return file_proto_shortlink_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *ExpandURLResponse) GetOriginalUrl() string {
@@ -209,25 +654,355 @@ func (x *ExpandURLResponse) GetOriginalUrl() string {
 	return ""
 }
 
+func (x *ExpandURLResponse) GetRules() []*RedirectRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+func (x *ExpandURLResponse) GetVariants() []*Variant {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+func (x *ExpandURLResponse) GetStickiness() Stickiness {
+	if x != nil {
+		return x.Stickiness
+	}
+	return Stickiness_STICKINESS_NONE
+}
+
+func (x *ExpandURLResponse) GetPasswordProtected() bool {
+	if x != nil {
+		return x.PasswordProtected
+	}
+	return false
+}
+
+func (x *ExpandURLResponse) GetSignatureRequired() bool {
+	if x != nil {
+		return x.SignatureRequired
+	}
+	return false
+}
+
+func (x *ExpandURLResponse) GetInterstitial() bool {
+	if x != nil {
+		return x.Interstitial
+	}
+	return false
+}
+
+func (x *ExpandURLResponse) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *ExpandURLResponse) GetRedirect() *RedirectOptions {
+	if x != nil {
+		return x.Redirect
+	}
+	return nil
+}
+
+// VerifyPasswordRequest contains a password attempt for a protected short URL
+type VerifyPasswordRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortId       string                 `protobuf:"bytes,1,opt,name=short_id,json=shortId,proto3" json:"short_id,omitempty"`
+	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyPasswordRequest) Reset() {
+	*x = VerifyPasswordRequest{}
+	mi := &file_proto_shortlink_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyPasswordRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyPasswordRequest) ProtoMessage() {}
+
+func (x *VerifyPasswordRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortlink_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyPasswordRequest.ProtoReflect.Descriptor instead.
+func (*VerifyPasswordRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortlink_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *VerifyPasswordRequest) GetShortId() string {
+	if x != nil {
+		return x.ShortId
+	}
+	return ""
+}
+
+func (x *VerifyPasswordRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+// VerifyPasswordResponse reports whether the password matched
+type VerifyPasswordResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Valid         bool                   `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyPasswordResponse) Reset() {
+	*x = VerifyPasswordResponse{}
+	mi := &file_proto_shortlink_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyPasswordResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyPasswordResponse) ProtoMessage() {}
+
+func (x *VerifyPasswordResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortlink_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyPasswordResponse.ProtoReflect.Descriptor instead.
+func (*VerifyPasswordResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortlink_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *VerifyPasswordResponse) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+// FindURLRequest looks up the oldest link of owner for normalized_url whose
+// rules, variants and options equal those of link. Password protected links
+// never match.
+type FindURLRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Owner         string                 `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	NormalizedUrl string                 `protobuf:"bytes,2,opt,name=normalized_url,json=normalizedUrl,proto3" json:"normalized_url,omitempty"`
+	Link          *ShortenURLRequest     `protobuf:"bytes,3,opt,name=link,proto3" json:"link,omitempty"` // original_url and password are ignored
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FindURLRequest) Reset() {
+	*x = FindURLRequest{}
+	mi := &file_proto_shortlink_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindURLRequest) ProtoMessage() {}
+
+func (x *FindURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortlink_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindURLRequest.ProtoReflect.Descriptor instead.
+func (*FindURLRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortlink_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *FindURLRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *FindURLRequest) GetNormalizedUrl() string {
+	if x != nil {
+		return x.NormalizedUrl
+	}
+	return ""
+}
+
+func (x *FindURLRequest) GetLink() *ShortenURLRequest {
+	if x != nil {
+		return x.Link
+	}
+	return nil
+}
+
+// FindURLResponse contains the matching short URL ID, empty if there is none
+type FindURLResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortId       string                 `protobuf:"bytes,1,opt,name=short_id,json=shortId,proto3" json:"short_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FindURLResponse) Reset() {
+	*x = FindURLResponse{}
+	mi := &file_proto_shortlink_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindURLResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindURLResponse) ProtoMessage() {}
+
+func (x *FindURLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortlink_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindURLResponse.ProtoReflect.Descriptor instead.
+func (*FindURLResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortlink_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *FindURLResponse) GetShortId() string {
+	if x != nil {
+		return x.ShortId
+	}
+	return ""
+}
+
 var File_proto_shortlink_proto protoreflect.FileDescriptor
 
 const file_proto_shortlink_proto_rawDesc = "" +
 	"\n" +
-	"\x15proto/shortlink.proto\x12\tshortlink\"6\n" +
+	"\x15proto/shortlink.proto\x12\tshortlink\"\xcc\x01\n" +
+	"\fRedirectRule\x12\x1c\n" +
+	"\tcountries\x18\x01 \x03(\tR\tcountries\x12\x1b\n" +
+	"\tdevice_os\x18\x02 \x03(\tR\bdeviceOs\x12\x1c\n" +
+	"\tlanguages\x18\x03 \x03(\tR\tlanguages\x12\x1d\n" +
+	"\n" +
+	"not_before\x18\x04 \x01(\x03R\tnotBefore\x12\x1b\n" +
+	"\tnot_after\x18\x05 \x01(\x03R\bnotAfter\x12'\n" +
+	"\x0fdestination_url\x18\x06 \x01(\tR\x0edestinationUrl\"^\n" +
+	"\aVariant\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12'\n" +
+	"\x0fdestination_url\x18\x02 \x01(\tR\x0edestinationUrl\x12\x16\n" +
+	"\x06weight\x18\x03 \x01(\rR\x06weight\"\x9c\x01\n" +
+	"\x0fRedirectOptions\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\x05R\x06status\x123\n" +
+	"\n" +
+	"query_mode\x18\x02 \x01(\x0e2\x14.shortlink.QueryModeR\tqueryMode\x12<\n" +
+	"\rfragment_mode\x18\x03 \x01(\x0e2\x17.shortlink.FragmentModeR\ffragmentMode\"\xb0\x03\n" +
 	"\x11ShortenURLRequest\x12!\n" +
-	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\"L\n" +
+	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\x12-\n" +
+	"\x05rules\x18\x02 \x03(\v2\x17.shortlink.RedirectRuleR\x05rules\x12.\n" +
+	"\bvariants\x18\x03 \x03(\v2\x12.shortlink.VariantR\bvariants\x125\n" +
+	"\n" +
+	"stickiness\x18\x04 \x01(\x0e2\x15.shortlink.StickinessR\n" +
+	"stickiness\x12\x1a\n" +
+	"\bpassword\x18\x05 \x01(\tR\bpassword\x12-\n" +
+	"\x12signature_required\x18\x06 \x01(\bR\x11signatureRequired\x12\"\n" +
+	"\finterstitial\x18\a \x01(\bR\finterstitial\x126\n" +
+	"\bredirect\x18\b \x01(\v2\x1a.shortlink.RedirectOptionsR\bredirect\x12\x14\n" +
+	"\x05owner\x18\t \x01(\tR\x05owner\x12%\n" +
+	"\x0enormalized_url\x18\n" +
+	" \x01(\tR\rnormalizedUrl\"L\n" +
 	"\x12ShortenURLResponse\x12\x19\n" +
 	"\bshort_id\x18\x01 \x01(\tR\ashortId\x12\x1b\n" +
 	"\tshort_url\x18\x02 \x01(\tR\bshortUrl\"-\n" +
 	"\x10ExpandURLRequest\x12\x19\n" +
-	"\bshort_id\x18\x01 \x01(\tR\ashortId\"6\n" +
+	"\bshort_id\x18\x01 \x01(\tR\ashortId\"\xa5\x03\n" +
 	"\x11ExpandURLResponse\x12!\n" +
-	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl2\x9f\x01\n" +
+	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\x12-\n" +
+	"\x05rules\x18\x02 \x03(\v2\x17.shortlink.RedirectRuleR\x05rules\x12.\n" +
+	"\bvariants\x18\x03 \x03(\v2\x12.shortlink.VariantR\bvariants\x125\n" +
+	"\n" +
+	"stickiness\x18\x04 \x01(\x0e2\x15.shortlink.StickinessR\n" +
+	"stickiness\x12-\n" +
+	"\x12password_protected\x18\x05 \x01(\bR\x11passwordProtected\x12-\n" +
+	"\x12signature_required\x18\x06 \x01(\bR\x11signatureRequired\x12\"\n" +
+	"\finterstitial\x18\a \x01(\bR\finterstitial\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\b \x01(\x03R\tcreatedAt\x126\n" +
+	"\bredirect\x18\t \x01(\v2\x1a.shortlink.RedirectOptionsR\bredirect\"N\n" +
+	"\x15VerifyPasswordRequest\x12\x19\n" +
+	"\bshort_id\x18\x01 \x01(\tR\ashortId\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\".\n" +
+	"\x16VerifyPasswordResponse\x12\x14\n" +
+	"\x05valid\x18\x01 \x01(\bR\x05valid\"\x7f\n" +
+	"\x0eFindURLRequest\x12\x14\n" +
+	"\x05owner\x18\x01 \x01(\tR\x05owner\x12%\n" +
+	"\x0enormalized_url\x18\x02 \x01(\tR\rnormalizedUrl\x120\n" +
+	"\x04link\x18\x03 \x01(\v2\x1c.shortlink.ShortenURLRequestR\x04link\",\n" +
+	"\x0fFindURLResponse\x12\x19\n" +
+	"\bshort_id\x18\x01 \x01(\tR\ashortId*M\n" +
+	"\n" +
+	"Stickiness\x12\x13\n" +
+	"\x0fSTICKINESS_NONE\x10\x00\x12\x15\n" +
+	"\x11STICKINESS_COOKIE\x10\x01\x12\x13\n" +
+	"\x0fSTICKINESS_HASH\x10\x02*|\n" +
+	"\tQueryMode\x12\x16\n" +
+	"\x12QUERY_MODE_DEFAULT\x10\x00\x12\x12\n" +
+	"\x0eQUERY_MODE_OFF\x10\x01\x12\x13\n" +
+	"\x0fQUERY_MODE_KEEP\x10\x02\x12\x17\n" +
+	"\x13QUERY_MODE_OVERRIDE\x10\x03\x12\x15\n" +
+	"\x11QUERY_MODE_APPEND\x10\x04*c\n" +
+	"\fFragmentMode\x12\x19\n" +
+	"\x15FRAGMENT_MODE_DEFAULT\x10\x00\x12\x1d\n" +
+	"\x19FRAGMENT_MODE_DESTINATION\x10\x01\x12\x19\n" +
+	"\x15FRAGMENT_MODE_VISITOR\x10\x022\xb8\x02\n" +
 	"\n" +
 	"URLService\x12I\n" +
 	"\n" +
 	"ShortenURL\x12\x1c.shortlink.ShortenURLRequest\x1a\x1d.shortlink.ShortenURLResponse\x12F\n" +
-	"\tExpandURL\x12\x1b.shortlink.ExpandURLRequest\x1a\x1c.shortlink.ExpandURLResponseB-Z+github.com/hohotang/shortlink-gateway/protob\x06proto3"
+	"\tExpandURL\x12\x1b.shortlink.ExpandURLRequest\x1a\x1c.shortlink.ExpandURLResponse\x12U\n" +
+	"\x0eVerifyPassword\x12 .shortlink.VerifyPasswordRequest\x1a!.shortlink.VerifyPasswordResponse\x12@\n" +
+	"\aFindURL\x12\x19.shortlink.FindURLRequest\x1a\x1a.shortlink.FindURLResponseB-Z+github.com/hohotang/shortlink-gateway/protob\x06proto3"
 
 var (
 	file_proto_shortlink_proto_rawDescOnce sync.Once
@@ -241,23 +1016,49 @@ func file_proto_shortlink_proto_rawDescGZIP() []byte {
 	return file_proto_shortlink_proto_rawDescData
 }
 
-var file_proto_shortlink_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_proto_shortlink_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_proto_shortlink_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_proto_shortlink_proto_goTypes = []any{
-	(*ShortenURLRequest)(nil),  // 0: shortlink.ShortenURLRequest
-	(*ShortenURLResponse)(nil), // 1: shortlink.ShortenURLResponse
-	(*ExpandURLRequest)(nil),   // 2: shortlink.ExpandURLRequest
-	(*ExpandURLResponse)(nil),  // 3: shortlink.ExpandURLResponse
+	(Stickiness)(0),                // 0: shortlink.Stickiness
+	(QueryMode)(0),                 // 1: shortlink.QueryMode
+	(FragmentMode)(0),              // 2: shortlink.FragmentMode
+	(*RedirectRule)(nil),           // 3: shortlink.RedirectRule
+	(*Variant)(nil),                // 4: shortlink.Variant
+	(*RedirectOptions)(nil),        // 5: shortlink.RedirectOptions
+	(*ShortenURLRequest)(nil),      // 6: shortlink.ShortenURLRequest
+	(*ShortenURLResponse)(nil),     // 7: shortlink.ShortenURLResponse
+	(*ExpandURLRequest)(nil),       // 8: shortlink.ExpandURLRequest
+	(*ExpandURLResponse)(nil),      // 9: shortlink.ExpandURLResponse
+	(*VerifyPasswordRequest)(nil),  // 10: shortlink.VerifyPasswordRequest
+	(*VerifyPasswordResponse)(nil), // 11: shortlink.VerifyPasswordResponse
+	(*FindURLRequest)(nil),         // 12: shortlink.FindURLRequest
+	(*FindURLResponse)(nil),        // 13: shortlink.FindURLResponse
 }
 var file_proto_shortlink_proto_depIdxs = []int32{
-	0, // 0: shortlink.URLService.ShortenURL:input_type -> shortlink.ShortenURLRequest
-	2, // 1: shortlink.URLService.ExpandURL:input_type -> shortlink.ExpandURLRequest
-	1, // 2: shortlink.URLService.ShortenURL:output_type -> shortlink.ShortenURLResponse
-	3, // 3: shortlink.URLService.ExpandURL:output_type -> shortlink.ExpandURLResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1,  // 0: shortlink.RedirectOptions.query_mode:type_name -> shortlink.QueryMode
+	2,  // 1: shortlink.RedirectOptions.fragment_mode:type_name -> shortlink.FragmentMode
+	3,  // 2: shortlink.ShortenURLRequest.rules:type_name -> shortlink.RedirectRule
+	4,  // 3: shortlink.ShortenURLRequest.variants:type_name -> shortlink.Variant
+	0,  // 4: shortlink.ShortenURLRequest.stickiness:type_name -> shortlink.Stickiness
+	5,  // 5: shortlink.ShortenURLRequest.redirect:type_name -> shortlink.RedirectOptions
+	3,  // 6: shortlink.ExpandURLResponse.rules:type_name -> shortlink.RedirectRule
+	4,  // 7: shortlink.ExpandURLResponse.variants:type_name -> shortlink.Variant
+	0,  // 8: shortlink.ExpandURLResponse.stickiness:type_name -> shortlink.Stickiness
+	5,  // 9: shortlink.ExpandURLResponse.redirect:type_name -> shortlink.RedirectOptions
+	6,  // 10: shortlink.FindURLRequest.link:type_name -> shortlink.ShortenURLRequest
+	6,  // 11: shortlink.URLService.ShortenURL:input_type -> shortlink.ShortenURLRequest
+	8,  // 12: shortlink.URLService.ExpandURL:input_type -> shortlink.ExpandURLRequest
+	10, // 13: shortlink.URLService.VerifyPassword:input_type -> shortlink.VerifyPasswordRequest
+	12, // 14: shortlink.URLService.FindURL:input_type -> shortlink.FindURLRequest
+	7,  // 15: shortlink.URLService.ShortenURL:output_type -> shortlink.ShortenURLResponse
+	9,  // 16: shortlink.URLService.ExpandURL:output_type -> shortlink.ExpandURLResponse
+	11, // 17: shortlink.URLService.VerifyPassword:output_type -> shortlink.VerifyPasswordResponse
+	13, // 18: shortlink.URLService.FindURL:output_type -> shortlink.FindURLResponse
+	15, // [15:19] is the sub-list for method output_type
+	11, // [11:15] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_proto_shortlink_proto_init() }
@@ -270,13 +1071,14 @@ func file_proto_shortlink_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_shortlink_proto_rawDesc), len(file_proto_shortlink_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      3,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_proto_shortlink_proto_goTypes,
 		DependencyIndexes: file_proto_shortlink_proto_depIdxs,
+		EnumInfos:         file_proto_shortlink_proto_enumTypes,
 		MessageInfos:      file_proto_shortlink_proto_msgTypes,
 	}.Build()
 	File_proto_shortlink_proto = out.File
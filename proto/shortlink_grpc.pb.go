@@ -19,8 +19,10 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	URLService_ShortenURL_FullMethodName = "/shortlink.URLService/ShortenURL"
-	URLService_ExpandURL_FullMethodName  = "/shortlink.URLService/ExpandURL"
+	URLService_ShortenURL_FullMethodName     = "/shortlink.URLService/ShortenURL"
+	URLService_ExpandURL_FullMethodName      = "/shortlink.URLService/ExpandURL"
+	URLService_VerifyPassword_FullMethodName = "/shortlink.URLService/VerifyPassword"
+	URLService_FindURL_FullMethodName        = "/shortlink.URLService/FindURL"
 )
 
 // URLServiceClient is the client API for URLService service.
@@ -33,6 +35,11 @@ type URLServiceClient interface {
 	ShortenURL(ctx context.Context, in *ShortenURLRequest, opts ...grpc.CallOption) (*ShortenURLResponse, error)
 	// ExpandURL resolves a short URL to its original URL
 	ExpandURL(ctx context.Context, in *ExpandURLRequest, opts ...grpc.CallOption) (*ExpandURLResponse, error)
+	// VerifyPassword checks a visitor-supplied password against the stored hash
+	VerifyPassword(ctx context.Context, in *VerifyPasswordRequest, opts ...grpc.CallOption) (*VerifyPasswordResponse, error)
+	// FindURL returns an existing link of an owner for a normalized URL, used to
+	// reuse links instead of creating duplicates
+	FindURL(ctx context.Context, in *FindURLRequest, opts ...grpc.CallOption) (*FindURLResponse, error)
 }
 
 type uRLServiceClient struct {
@@ -63,6 +70,26 @@ func (c *uRLServiceClient) ExpandURL(ctx context.Context, in *ExpandURLRequest,
 	return out, nil
 }
 
+func (c *uRLServiceClient) VerifyPassword(ctx context.Context, in *VerifyPasswordRequest, opts ...grpc.CallOption) (*VerifyPasswordResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VerifyPasswordResponse)
+	err := c.cc.Invoke(ctx, URLService_VerifyPassword_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLServiceClient) FindURL(ctx context.Context, in *FindURLRequest, opts ...grpc.CallOption) (*FindURLResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FindURLResponse)
+	err := c.cc.Invoke(ctx, URLService_FindURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // URLServiceServer is the server API for URLService service.
 // All implementations must embed UnimplementedURLServiceServer
 // for forward compatibility.
@@ -73,6 +100,11 @@ type URLServiceServer interface {
 	ShortenURL(context.Context, *ShortenURLRequest) (*ShortenURLResponse, error)
 	// ExpandURL resolves a short URL to its original URL
 	ExpandURL(context.Context, *ExpandURLRequest) (*ExpandURLResponse, error)
+	// VerifyPassword checks a visitor-supplied password against the stored hash
+	VerifyPassword(context.Context, *VerifyPasswordRequest) (*VerifyPasswordResponse, error)
+	// FindURL returns an existing link of an owner for a normalized URL, used to
+	// reuse links instead of creating duplicates
+	FindURL(context.Context, *FindURLRequest) (*FindURLResponse, error)
 	mustEmbedUnimplementedURLServiceServer()
 }
 
@@ -89,6 +121,12 @@ func (UnimplementedURLServiceServer) ShortenURL(context.Context, *ShortenURLRequ
 func (UnimplementedURLServiceServer) ExpandURL(context.Context, *ExpandURLRequest) (*ExpandURLResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ExpandURL not implemented")
 }
+func (UnimplementedURLServiceServer) VerifyPassword(context.Context, *VerifyPasswordRequest) (*VerifyPasswordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyPassword not implemented")
+}
+func (UnimplementedURLServiceServer) FindURL(context.Context, *FindURLRequest) (*FindURLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindURL not implemented")
+}
 func (UnimplementedURLServiceServer) mustEmbedUnimplementedURLServiceServer() {}
 func (UnimplementedURLServiceServer) testEmbeddedByValue()                    {}
 
@@ -146,6 +184,42 @@ func _URLService_ExpandURL_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _URLService_VerifyPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyPasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLServiceServer).VerifyPassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLService_VerifyPassword_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLServiceServer).VerifyPassword(ctx, req.(*VerifyPasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLService_FindURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLServiceServer).FindURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLService_FindURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLServiceServer).FindURL(ctx, req.(*FindURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // URLService_ServiceDesc is the grpc.ServiceDesc for URLService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -161,6 +235,14 @@ var URLService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ExpandURL",
 			Handler:    _URLService_ExpandURL_Handler,
 		},
+		{
+			MethodName: "VerifyPassword",
+			Handler:    _URLService_VerifyPassword_Handler,
+		},
+		{
+			MethodName: "FindURL",
+			Handler:    _URLService_FindURL_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/shortlink.proto",